@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// entryClock returns the time of day an entry is planned for. An at: tag
+// wins over the time on the scheduled date, which wins over the due time.
+func entryClock(e *ast.Entry) (time.Duration, bool) {
+	if at, ok := e.Tag("at"); ok {
+		if start, _, err := dates.ParseClockRange(at); err == nil {
+			return start, true
+		}
+	}
+	if scheduledFor, ok := e.ScheduledFor(); ok {
+		if _, clock, ok := dates.CutTime(scheduledFor); ok {
+			start, _ := dates.ParseClock(clock)
+			return start, true
+		}
+	}
+	if dueDate, ok := e.DueDate(); ok {
+		if _, clock, ok := dates.CutTime(dueDate); ok {
+			start, _ := dates.ParseClock(clock)
+			return start, true
+		}
+	}
+	return 0, false
+}
+
+// keepScheduledTime copies the time of day from the scheduled date to an at:
+// tag, so that it survives the scheduled date being removed.
+func keepScheduledTime(e *ast.Entry) {
+	if _, ok := e.Tag("at"); ok {
+		return
+	}
+	scheduledFor, ok := e.ScheduledFor()
+	if !ok {
+		return
+	}
+	_, clock, ok := dates.CutTime(scheduledFor)
+	if !ok {
+		return
+	}
+	start, _ := dates.ParseClock(clock)
+	e.Description = append(e.Description, &ast.DescriptionPart{
+		SpecialTag: &ast.SpecialTag{Key: "at", Value: dates.FormatClock(start)},
+	})
+}
+
+func runAgenda(args []string) error {
+	fs := flag.NewFlagSet("agenda", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "-", "todo.txt file path to read")
+	fs.Parse(args)
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	t, err := loadTodoTxt(*filename, now)
+	if err != nil {
+		return err
+	}
+	return dumpAgenda(os.Stdout, t, now)
+}
+
+// dumpAgenda prints today's timeline: the entries in Today and Evening with
+// a time of day in order, followed by the rest.
+func dumpAgenda(out io.Writer, t ast.TodoTxt, now time.Time) error {
+	type slot struct {
+		start time.Duration
+		when  string
+		e     *ast.Entry
+	}
+	var timeline []slot
+	var anytime, evening []*ast.Entry
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		if e.Completed || (heading != "Today" && heading != "Evening") {
+			return nil
+		}
+		start, ok := entryClock(e)
+		if !ok {
+			if heading == "Evening" {
+				evening = append(evening, e)
+			} else {
+				anytime = append(anytime, e)
+			}
+			return nil
+		}
+		when := dates.FormatClock(start)
+		if at, ok := e.Tag("at"); ok {
+			if start, end, err := dates.ParseClockRange(at); err == nil && end != start {
+				when += "-" + dates.FormatClock(end)
+			}
+		}
+		timeline = append(timeline, slot{start, when, e})
+		return nil
+	})
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].start < timeline[j].start
+	})
+
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "Agenda for %s\n", now.Format("Monday 2006-01-02"))
+	if len(timeline) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, s := range timeline {
+		fmt.Fprintf(w, "%-12s %s\n", s.when, agendaText(s.e))
+	}
+	for _, section := range []struct {
+		title   string
+		entries []*ast.Entry
+	}{{"Anytime", anytime}, {"Evening", evening}} {
+		if len(section.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\n", section.title)
+		for _, e := range section.entries {
+			fmt.Fprintf(w, "%-12s %s\n", "", agendaText(e))
+		}
+	}
+	return w.Flush()
+}
+
+// agendaText is the entry's description without the at: tag, which the
+// agenda already shows.
+func agendaText(e *ast.Entry) string {
+	shown := *e
+	shown.RemoveTag("at")
+	return shown.DescriptionText()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAgenda(t *testing.T) {
+	defer func(hour int) { *eveningHour = hour }(*eveningHour)
+	*eveningHour = 18
+
+	input := strings.Join([]string{
+		"# Inbox",
+		"",
+		"  dinner with +family s:t@7pm",
+		"  dentist s:t@14:00",
+		"  standup s:today at:9:30-10:00",
+		"  call mom s:t",
+		"",
+		"# Evening",
+		"",
+		"  read a book",
+	}, "\n")
+	want := strings.Join([]string{
+		"Agenda for Saturday 2022-01-01",
+		"",
+		"09:30-10:00  standup",
+		"14:00        dentist",
+		"19:00        dinner with +family",
+		"",
+		"Anytime",
+		"             call mom",
+		"",
+		"Evening",
+		"             read a book",
+	}, "\n") + "\n"
+
+	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
+	todo, err := parseTodoTxt([]byte(input), now)
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+	if evening := findGrouping(&todo, "Evening"); evening.Len() != 2 {
+		t.Errorf("want 2 entries in Evening, got %d", evening.Len())
+	}
+
+	var got bytes.Buffer
+	if err := dumpAgenda(&got, todo, now); err != nil {
+		t.Fatalf("dumpAgenda failed: %v", err)
+	}
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("dumpAgenda returned unexpected result (-got,+want):\n%s", diff)
+	}
+}
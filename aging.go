@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// agingHeaders are the headers whose entries go stale if left alone.
+var agingHeaders = map[string]bool{
+	"":      true, // Entries before any header land in the Inbox.
+	"Inbox": true,
+	"Next":  true,
+}
+
+// ageEntry tags stale entries with their age and sends entries that have been
+// stale for too long to Someday. Demoted entries get a move tag, so the
+// Someday header rules take care of the actual move.
+func ageEntry(now time.Time, heading string, e *ast.Entry) {
+	if e.Completed {
+		return
+	}
+	// The age is worked out again on every run, so an entry that has left
+	// the aging headers, or is formatted with aging off, loses it.
+	e.RemoveTag("age")
+	if !agingHeaders[heading] {
+		return
+	}
+
+	age, ok := entryAge(now, e)
+	if !ok {
+		return
+	}
+	if *demoteDays > 0 && age >= *demoteDays {
+		if heading == "" {
+			heading = "Inbox"
+		}
+		e.Description = append(e.Description, &ast.DescriptionPart{
+			SpecialTag: &ast.SpecialTag{Key: "move", Value: "someday"},
+		})
+		e.Notes = append(e.Notes, ast.NoteLine{
+			Text: strings.Fields(fmt.Sprintf("moved to Someday after %d days in %s", age, heading)),
+		})
+		return
+	}
+	if *staleDays > 0 && age >= *staleDays {
+		e.Description = append(e.Description, &ast.DescriptionPart{
+			SpecialTag: &ast.SpecialTag{Key: "age", Value: fmt.Sprintf("%dd", age)},
+		})
+	}
+}
+
+// entryAge returns how many days ago an unplanned entry was created. Entries
+// that are scheduled, due, or already moving somewhere do not age.
+func entryAge(now time.Time, e *ast.Entry) (int, bool) {
+	if _, ok := e.ScheduledFor(); ok {
+		return 0, false
+	}
+	if _, ok := e.DueDate(); ok {
+		return 0, false
+	}
+	if _, ok := e.Tag("move"); ok {
+		return 0, false
+	}
+	if e.CreationDate == nil || !e.CreationDate.Valid() {
+		return 0, false
+	}
+	return e.CreationDate.DaysUntil(dates.DateOf(now)), true
+}
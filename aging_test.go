@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFmtAging(t *testing.T) {
+	defer func(stale, demote int) {
+		*staleDays, *demoteDays = stale, demote
+	}(*staleDays, *demoteDays)
+	*staleDays, *demoteDays = 14, 60
+
+	input := strings.Join([]string{
+		"# Inbox",
+		"",
+		"  2021-12-25 new idea",
+		"  2021-12-01 old idea age:3d",
+		"",
+		"# Next",
+		"",
+		"  2021-10-01 ancient chore",
+		"  2021-10-01 ancient but due due:2022-02-01",
+		"",
+		"# Someday",
+		"",
+		"  2021-01-01 learn the banjo",
+	}, "\n")
+	want := strings.Join([]string{
+		"# Inbox",
+		"",
+		"  2021-12-25 new idea",
+		"  2021-12-01 old idea age:31d",
+		"",
+		"# Next",
+		"",
+		"  2021-10-01 ancient but due due:2022-02-01",
+		"",
+		"# Someday",
+		"",
+		"  2021-10-01 ancient chore",
+		"           | moved to Someday after 92 days in Next",
+		"  2021-01-01 learn the banjo",
+	}, "\n") + "\n"
+
+	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		got := new(bytes.Buffer)
+		if err := Fmt(now, got, []byte(input)); err != nil {
+			t.Fatalf("unexpected error from Fmt: %v", err)
+		}
+		if diff := cmp.Diff(got.String(), want); diff != "" {
+			t.Fatalf("Fmt() pass %d returned unexpected result (-got,+want):\n%s", i+1, diff)
+		}
+		input = got.String()
+	}
+}
+
+func TestFmtAgingDropsAge(t *testing.T) {
+	defer func(stale, demote int) {
+		*staleDays, *demoteDays = stale, demote
+	}(*staleDays, *demoteDays)
+	*demoteDays = 0
+
+	table := []struct {
+		name  string
+		stale int
+		input string
+		want  string
+	}{{
+		name:  "scheduled out of Next",
+		stale: 14,
+		input: "# Next\n\n  2021-12-01 old chore age:31d sched:today\n",
+		want:  "# Today\n\n  2021-12-01 old chore\n",
+	}, {
+		name:  "stale off",
+		stale: 0,
+		input: "# Inbox\n\n  2021-12-01 old idea age:31d\n",
+		want:  "# Inbox\n\n  2021-12-01 old idea\n",
+	}}
+	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
+	for _, tc := range table {
+		*staleDays = tc.stale
+		got := new(bytes.Buffer)
+		if err := Fmt(now, got, []byte(tc.input)); err != nil {
+			t.Fatalf("%s: unexpected error from Fmt: %v", tc.name, err)
+		}
+		if diff := cmp.Diff(got.String(), tc.want); diff != "" {
+			t.Errorf("%s: Fmt() returned unexpected result (-got,+want):\n%s", tc.name, diff)
+		}
+	}
+}
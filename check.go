@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// moveTargets are the headers a scheduled tag can name instead of a date.
+var moveTargets = map[string]bool{
+	"next":    true,
+	"someday": true,
+	"waiting": true,
+	"evening": true,
+}
+
+// diagnostic is a problem with a todo file that the formatter works around
+// but that the author probably wants to fix.
+type diagnostic struct {
+	Pos     lexer.Position
+	Message string
+}
+
+func (d diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.Pos.Filename, d.Pos.Line, d.Pos.Column, d.Message)
+}
+
+// runCheck prints problems with the file in a form vim's quickfix list
+// understands.
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "-", "todo.txt file path to check")
+	fs.Parse(args)
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	files, err := loadTodoFiles(*filename)
+	if err != nil {
+		return err
+	}
+
+	var diagnostics []diagnostic
+	for _, f := range files {
+		diagnostics = append(diagnostics, checkTodoTxt(f.Tree, now)...)
+	}
+	for _, d := range diagnostics {
+		fmt.Println(d)
+	}
+	if len(diagnostics) > 0 {
+		return fmt.Errorf("found %d problems", len(diagnostics))
+	}
+	return nil
+}
+
+// checkTodoTxt finds dates that do not exist or cannot be understood, which
+// would otherwise be sorted after everything else without a word, and entries
+// left conflicting by a merge.
+func checkTodoTxt(t ast.TodoTxt, now time.Time) []diagnostic {
+	var diagnostics []diagnostic
+	report := func(e *ast.Entry, format string, args ...any) {
+		diagnostics = append(diagnostics, diagnostic{
+			Pos:     e.Pos,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		if e.CompletionDate != nil && !e.CompletionDate.Valid() {
+			report(e, "completion date: %v", e.CompletionDate.Err())
+		}
+		if e.CreationDate != nil && !e.CreationDate.Valid() {
+			report(e, "creation date: %v", e.CreationDate.Err())
+		}
+		if e.CompletionDate != nil && e.CreationDate != nil &&
+			e.CompletionDate.Valid() && e.CreationDate.Valid() &&
+			e.CompletionDate.Before(*e.CreationDate) {
+			report(e, "completed on %s, before it was created on %s", e.CompletionDate, e.CreationDate)
+		}
+
+		for _, dp := range e.Description {
+			tag := dp.SpecialTag
+			if tag == nil {
+				continue
+			}
+			switch {
+			case tag.Key == "due" || tag.Key == followTag || ast.StringIsScheduled(tag.Key):
+				day, _, _ := dates.CutTime(tag.Value)
+				if day == "t" || moveTargets[strings.ToLower(tag.Value)] {
+					continue
+				}
+				if _, err := tag.Date(now); err != nil {
+					report(e, "%s:%s: %v", tag.Key, tag.Value, err)
+				}
+			case tag.Key == snoozeTag:
+				if _, err := snoozeUntil(now, tag.Value); err != nil {
+					report(e, "%s:%s: %v", tag.Key, tag.Value, err)
+				}
+			case tag.Key == "at":
+				if _, _, err := dates.ParseClockRange(tag.Value); err != nil {
+					report(e, "at:%s: %v", tag.Value, err)
+				}
+			case tag.Key == conflictTag:
+				report(e, "merge conflict, keep this version or the other one")
+			}
+		}
+		return nil
+	})
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Pos.Offset < diagnostics[j].Pos.Offset
+	})
+	return diagnostics
+}
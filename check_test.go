@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheck(t *testing.T) {
+	input := strings.Join([]string{
+		"# Inbox",
+		"",
+		"  2024-02-30 no such day",
+		"  2024-01-01 due on no such month due:2024-13-01",
+		"  2024-01-01 move it s:next",
+		"  2024-01-01 fine s:fri@2pm",
+		"  2024-01-01 meet at:25:00",
+		"  2024-01-01 pick one conflict:ours",
+		"",
+		"# Logged",
+		"",
+		"x 2024-01-01 2024-02-01 time travel",
+	}, "\n")
+
+	todo, err := parseFile("todo.txt", []byte(input))
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+	now := time.Date(2024, time.January, 01, 0, 0, 0, 0, time.UTC)
+	var got []string
+	for _, d := range checkTodoTxt(todo, now) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`todo.txt:3:3: creation date: invalid date "2024-02-30": day out of range`,
+		`todo.txt:4:3: due:2024-13-01: invalid date "2024-13-01": month out of range`,
+		`todo.txt:7:3: at:25:00: time "25:00" out of range`,
+		`todo.txt:8:3: merge conflict, keep this version or the other one`,
+		`todo.txt:12:1: completed on 2024-01-01, before it was created on 2024-02-01`,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("checkTodoTxt returned unexpected result (-got,+want):\n%s", diff)
+	}
+}
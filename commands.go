@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/parse"
+)
+
+// commands are the subcommands vogon understands. Running vogon without a
+// subcommand formats the input, which is what the vim plugin relies on.
+var commands = map[string]func(args []string) error{
+	"view":    runView,
+	"review":  runReview,
+	"serve":   runServe,
+	"stats":   runStats,
+	"agenda":  runAgenda,
+	"check":   runCheck,
+	"export":  runExport,
+	"focus":   runFocus,
+	"import":  runImport,
+	"merge":   runMerge,
+	"render":  runRender,
+	"snooze":  runSnooze,
+	"sync":    runSync,
+	"undo":    runUndo,
+	"waiting": runWaiting,
+	"watch":   runWatch,
+}
+
+func readInput(filename string) ([]byte, error) {
+	var input io.ReadCloser
+	if filename == "-" {
+		input = os.Stdin
+	} else {
+		var err error
+		input, err = os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer input.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, input); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// loadTodoTxt reads, parses, and compiles a todo file along with every file
+// it includes, so that subcommands see all of them as they would look after
+// formatting.
+func loadTodoTxt(filename string, now time.Time) (ast.TodoTxt, error) {
+	files, err := loadTodoFiles(filename)
+	if err != nil {
+		return ast.TodoTxt{}, err
+	}
+	compileFiles(files, now)
+	return mergeFiles(files), nil
+}
+
+func parseTodoTxt(input []byte, now time.Time) (ast.TodoTxt, error) {
+	t, err := parseFile("", input)
+	if err != nil {
+		return t, err
+	}
+	return compileTodoTxt(t, now), nil
+}
+
+// parseFile parses a todo file without formatting it. Entry positions refer
+// to filename.
+func parseFile(filename string, input []byte) (ast.TodoTxt, error) {
+	t, err := parse.Parse(filename, input)
+	if err != nil {
+		return t, fmt.Errorf("parse error: %w", err)
+	}
+	return t, nil
+}
+
+// parseEntry parses a single entry written as it would be in a todo file.
+func parseEntry(text string) (*ast.Entry, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.Contains(text, "\n") {
+		return nil, fmt.Errorf("want one line of entry text, got %q", text)
+	}
+	t, err := parseFile("", []byte(text+"\n"))
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Groupings) != 1 || len(t.Groupings[0].Header) != 0 || t.Groupings[0].Len() != 1 {
+		return nil, fmt.Errorf("want exactly one entry, got %q", text)
+	}
+	e := t.Groupings[0].Blocks[0].Children[0]
+	e.Source = nil // Written the way the formatter writes new entries.
+	return e, nil
+}
+
+// moveEntry takes e out of t, if it is there, and adds it under header. The
+// header is matched without regard to case; a header that does not exist yet
+// is added. Moves to the manual headers are left to a move tag, as if the
+// entry had been moved by hand.
+func moveEntry(t *ast.TodoTxt, e *ast.Entry, header string) {
+	if target := strings.ToLower(header); moveTargets[target] {
+		e.RemoveTag("move")
+		e.Description = append(e.Description, &ast.DescriptionPart{
+			SpecialTag: &ast.SpecialTag{Key: "move", Value: target},
+		})
+		header = ""
+	}
+	removeEntry(t, e)
+	for gi, g := range t.Groupings {
+		if strings.EqualFold(strings.Join(g.Header, " "), header) {
+			t.Groupings[gi].Blocks = append(t.Groupings[gi].Blocks, ast.Block{Children: []*ast.Entry{e}})
+			return
+		}
+	}
+	t.Groupings = append(t.Groupings, ast.Grouping{
+		Header: strings.Fields(header),
+		Blocks: []ast.Block{{Children: []*ast.Entry{e}}},
+	})
+}
+
+// removeEntry takes e out of t, if it is there.
+func removeEntry(t *ast.TodoTxt, e *ast.Entry) {
+	for gi := range t.Groupings {
+		for bi := range t.Groupings[gi].Blocks {
+			ast.SliceRemove(&t.Groupings[gi].Blocks[bi].Children, func(other *ast.Entry) bool {
+				return other == e
+			})
+		}
+	}
+}
+
+// writeFileAtomic replaces filename with contents, so that an editor or a
+// concurrent run never sees a partial write.
+func writeFileAtomic(filename string, contents []byte) error {
+	tmp, err := stageFile(filename, contents)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// stageFile writes contents to a temporary file next to filename, with the
+// same permissions, and returns its name. Renaming it over filename finishes
+// the write; removing it abandons it.
+func stageFile(filename string, contents []byte) (string, error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return "", err
+	}
+	if _, err = tmp.Write(contents); err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
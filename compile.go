@@ -17,10 +17,24 @@ type HeaderCompiler struct {
 
 func Compile(t ast.TodoTxt, compilers []HeaderCompiler) ast.TodoTxt {
 	newEntries := make(map[string][]ast.Block)
+	sources := make(map[string]string)
 
 	for _, grouping := range t.Groupings {
 		origHeader := strings.Join(grouping.Header, " ")
+		if _, ok := sources[origHeader]; !ok {
+			sources[origHeader] = grouping.Source
+		}
 		for blockNum, block := range grouping.Blocks {
+			// The lines between blocks stay where they were, even if every
+			// entry after them moves.
+			if len(block.Leading) > 0 {
+				for len(newEntries[origHeader]) <= blockNum {
+					newEntries[origHeader] = append(newEntries[origHeader], ast.Block{})
+				}
+				// A header written twice keeps the lines of both.
+				leading := &newEntries[origHeader][blockNum].Leading
+				*leading = append(*leading, block.Leading...)
+			}
 			for _, e := range block.Children {
 				insertBlock := blockNum
 				dstHeader := origHeader
@@ -86,16 +100,25 @@ func Compile(t ast.TodoTxt, compilers []HeaderCompiler) ast.TodoTxt {
 		result.Groupings = append(result.Groupings, ast.Grouping{
 			Header: []string{header}, // This may not be strictly correct, but the result is the same.
 			Blocks: blocks,
+			Source: sources[header],
 		})
 	}
 
+	sortGroupings(result.Groupings, t.Groupings)
+	return result
+}
+
+// sortGroupings puts groupings in the canonical header order. Headers that
+// are not official keep the order they had in original.
+func sortGroupings(groupings []ast.Grouping, original []ast.Grouping) {
 	existingPriorities := map[string]int{}
-	for i, g := range t.Groupings {
+	for i, g := range original {
 		existingPriorities[strings.Join(g.Header, " ")] = i
 	}
 
 	// Sort the groupings by desired order.
 	headingPriority := map[string]int{
+		"":          0, // What comes before the first header.
 		"Inbox":     10,
 		"Today":     20,
 		"Evening":   21,
@@ -105,9 +128,9 @@ func Compile(t ast.TodoTxt, compilers []HeaderCompiler) ast.TodoTxt {
 		"Someday":   50,
 		"Logged":    999,
 	}
-	sort.SliceStable(result.Groupings, func(i, j int) bool {
-		leftHeader := strings.Join(result.Groupings[i].Header, " ")
-		rightHeader := strings.Join(result.Groupings[j].Header, " ")
+	sort.SliceStable(groupings, func(i, j int) bool {
+		leftHeader := strings.Join(groupings[i].Header, " ")
+		rightHeader := strings.Join(groupings[j].Header, " ")
 		left, leftKnown := headingPriority[leftHeader]
 		right, rightKnown := headingPriority[rightHeader]
 
@@ -128,8 +151,6 @@ func Compile(t ast.TodoTxt, compilers []HeaderCompiler) ast.TodoTxt {
 
 		return left < right
 	})
-
-	return result
 }
 
 func sliceToMap[T any](l []T, f func(T) string) map[string]T {
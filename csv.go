@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// csvFields are the columns of an exported CSV file. Each tag key gets a
+// column of its own between contexts and notes.
+var csvFields = []string{
+	"header",
+	"completed",
+	"priority",
+	"completion_date",
+	"creation_date",
+	"description",
+	"projects",
+	"contexts",
+}
+
+const csvNotes = "notes"
+
+// exportCSV writes one row per entry. Projects and contexts are separated by
+// spaces and notes by newlines.
+func exportCSV(out io.Writer, t ast.TodoTxt) error {
+	var tagKeys []string
+	seen := make(map[string]bool)
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		for _, dp := range e.Description {
+			if dp.SpecialTag != nil && !seen[dp.SpecialTag.Key] {
+				seen[dp.SpecialTag.Key] = true
+				tagKeys = append(tagKeys, dp.SpecialTag.Key)
+			}
+		}
+		return nil
+	})
+	sort.Strings(tagKeys)
+
+	w := csv.NewWriter(out)
+	header := append(append(append([]string{}, csvFields...), tagKeys...), csvNotes)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for i, g := range t.Groupings {
+		heading := strings.Join(g.Header, " ")
+		if heading == "" && i == 0 {
+			heading = "Inbox"
+		}
+		for _, b := range g.Blocks {
+			for _, e := range b.Children {
+				if e == nil {
+					continue
+				}
+				if err := w.Write(csvRow(heading, e, tagKeys)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func csvRow(heading string, e *ast.Entry, tagKeys []string) []string {
+	var priority, completed, created string
+	if e.Priority != nil {
+		priority = strings.Trim(*e.Priority, "()")
+	}
+	if e.CompletionDate != nil {
+		completed = e.CompletionDate.String()
+	}
+	if e.CreationDate != nil {
+		created = e.CreationDate.String()
+	}
+	var words []string
+	for _, dp := range e.Description {
+		words = append(words, dp.Text...)
+	}
+	row := []string{
+		heading,
+		fmt.Sprint(e.Completed),
+		priority,
+		completed,
+		created,
+		strings.Join(words, " "),
+		strings.Join(e.Projects(), " "),
+		strings.Join(e.Contexts(), " "),
+	}
+	for _, key := range tagKeys {
+		value, _ := e.Tag(key)
+		row = append(row, value)
+	}
+	var notes []string
+	for _, line := range e.Notes {
+		notes = append(notes, strings.Join(line.Text, " "))
+	}
+	return append(row, strings.Join(notes, "\n"))
+}
+
+// parseColumnMap parses a mapping from entry fields to CSV columns, like
+// "description=Title,due=Due Date". Fields that are not in csvFields or
+// notes are tag keys.
+func parseColumnMap(mapping string) (map[string]string, error) {
+	result := make(map[string]string)
+	if mapping == "" {
+		return result, nil
+	}
+	for _, pair := range strings.Split(mapping, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("bad column mapping %q, want field=Column", pair)
+		}
+		if strings.ContainsAny(field, " \t:") {
+			return nil, fmt.Errorf("bad field %q in column mapping", field)
+		}
+		result[field] = column
+	}
+	return result, nil
+}
+
+// importCSV reads entries and the headers they belong under from a CSV file
+// with a header row. Without a mapping, columns are read by the names
+// exportCSV gives them, and every other column with a name that could be a tag
+// key is a tag. With a mapping, only the mapped columns are read.
+func importCSV(in io.Reader, mapping map[string]string) ([]importedEntry, error) {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	columns, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	if len(mapping) == 0 {
+		mapping = make(map[string]string)
+		for _, column := range columns {
+			if column = strings.TrimSpace(column); column != "" && !strings.ContainsAny(column, " \t:") {
+				mapping[column] = column
+			}
+		}
+	}
+	index := make(map[string]int) // Field to column index.
+	for field, column := range mapping {
+		found := false
+		for i, c := range columns {
+			if strings.EqualFold(strings.TrimSpace(c), column) {
+				index[field], found = i, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no column %q for %s", column, field)
+		}
+	}
+	var tagKeys []string
+	for field := range index {
+		if !slices.Contains(csvFields, field) && field != csvNotes {
+			tagKeys = append(tagKeys, field)
+		}
+	}
+	sort.Strings(tagKeys)
+
+	var result []importedEntry
+	for line := 2; ; line++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		get := func(field string) string {
+			if i, ok := index[field]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		imported, err := csvEntry(get, tagKeys)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		result = append(result, imported)
+	}
+	return result, nil
+}
+
+// csvEntry builds an entry from the fields of a row. The columns are taken as
+// they are, so a description is never read as todo.txt syntax.
+func csvEntry(get func(field string) string, tagKeys []string) (importedEntry, error) {
+	description, err := importedDescription(get("description"))
+	if err != nil {
+		return importedEntry{}, err
+	}
+	e := &ast.Entry{Completed: isDone(get("completed")), Description: description}
+	if p := strings.Trim(get("priority"), "()"); p != "" {
+		if len(p) != 1 || p[0] < 'A' || p[0] > 'Z' {
+			return importedEntry{}, fmt.Errorf("bad priority %q, want A to Z", p)
+		}
+		priority := "(" + p + ")"
+		e.Priority = &priority
+	}
+	for _, field := range []struct {
+		name string
+		date **dates.Date
+	}{
+		{"completion_date", &e.CompletionDate},
+		{"creation_date", &e.CreationDate},
+	} {
+		if value := get(field.name); value != "" {
+			d, err := dates.ParseDate(value)
+			if err != nil {
+				return importedEntry{}, fmt.Errorf("%s: %w", field.name, err)
+			}
+			*field.date = &d
+		}
+	}
+	if !e.Completed {
+		e.CompletionDate = nil
+	}
+
+	for _, p := range splitList(get("projects")) {
+		addProject(e, strings.TrimPrefix(p, "+"))
+	}
+	for _, c := range splitList(get("contexts")) {
+		addContext(e, strings.TrimPrefix(c, "@"))
+	}
+	for _, key := range tagKeys {
+		if value := strings.Join(strings.Fields(get(key)), "_"); value != "" {
+			addTag(e, key, value)
+		}
+	}
+	for _, line := range strings.Split(get(csvNotes), "\n") {
+		if text := strings.Fields(line); len(text) > 0 {
+			e.Notes = append(e.Notes, ast.NoteLine{Text: text})
+		}
+	}
+	return importedEntry{Header: get("header"), Entry: e}, nil
+}
+
+// isDone reads the many ways spreadsheets and issue trackers say a task is
+// done.
+func isDone(value string) bool {
+	switch strings.ToLower(value) {
+	case "x", "true", "yes", "y", "1", "done", "closed", "completed", "resolved":
+		return true
+	}
+	return false
+}
+
+// splitList splits a list of projects or contexts on spaces or commas.
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
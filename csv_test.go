@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	input := strings.Join([]string{
+		"# Next",
+		"",
+		"  (A) 2022-01-01 write report +work @desk due:2022-01-07",
+		"           | ask Sam, \"now\"",
+		"",
+		"# Logged",
+		"",
+		"x 2022-01-04 2022-01-01 send invoices +work",
+	}, "\n") + "\n"
+	now := time.Date(2022, time.January, 05, 0, 0, 0, 0, time.UTC)
+	todo, err := parseTodoTxt([]byte(input), now)
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+
+	var csv bytes.Buffer
+	if err := exportCSV(&csv, todo); err != nil {
+		t.Fatalf("exportCSV failed: %v", err)
+	}
+	wantCSV := strings.Join([]string{
+		"header,completed,priority,completion_date,creation_date,description,projects,contexts,due,notes",
+		`Next,false,A,,2022-01-01,write report,work,desk,2022-01-07,"ask Sam, ""now"""`,
+		"Logged,true,,2022-01-04,2022-01-01,send invoices,work,,,",
+	}, "\n") + "\n"
+	if diff := cmp.Diff(csv.String(), wantCSV); diff != "" {
+		t.Errorf("exportCSV returned unexpected result (-got,+want):\n%s", diff)
+	}
+
+	entries, err := importCSV(&csv, map[string]string{})
+	if err != nil {
+		t.Fatalf("importCSV failed: %v", err)
+	}
+	var imported ast.TodoTxt
+	addImported(&imported, entries)
+	var got bytes.Buffer
+	if err := compileTodoTxt(imported, now).DumpText(&got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got.String(), input); diff != "" {
+		t.Errorf("imported entries differ from exported ones (-got,+want):\n%s", diff)
+	}
+}
+
+func TestImportCSVMapping(t *testing.T) {
+	input := strings.Join([]string{
+		"Title,State,Due Date,Labels,Assignee",
+		"Fix bug,open,2022-01-05,\"backend, api\",sam",
+		"Ship it,closed,,,",
+		",open,,,",
+	}, "\n")
+	mapping, err := parseColumnMap("description=Title, completed=State, due=Due Date, projects=Labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = importCSV(strings.NewReader(input), mapping)
+	if err == nil || err.Error() != "line 4: missing description" {
+		t.Errorf("want missing description error, got %v", err)
+	}
+
+	entries, err := importCSV(strings.NewReader(strings.TrimSuffix(input, ",open,,,")), mapping)
+	if err != nil {
+		t.Fatalf("importCSV failed: %v", err)
+	}
+	var got []string
+	for _, imported := range entries {
+		got = append(got, strings.TrimSpace(entryText(imported.Entry)))
+	}
+	want := []string{
+		"Fix bug +backend +api due:2022-01-05",
+		"x Ship it",
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("importCSV returned unexpected result (-got,+want):\n%s", diff)
+	}
+}
+
+func TestImportCSVDescription(t *testing.T) {
+	input := strings.Join([]string{
+		"description,completed,priority",
+		"x marks the spot,,",
+		"2023-05-01 retro notes,,B",
+		"mix at ratio 3:1 @garage,yes,",
+	}, "\n")
+	entries, err := importCSV(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type fields struct {
+		Completed   bool
+		Created     bool
+		Description string
+		Tags        int
+		Contexts    []string
+	}
+	var got []fields
+	for _, imported := range entries {
+		e := imported.Entry
+		f := fields{Completed: e.Completed, Created: e.CreationDate != nil, Description: e.DescriptionText()}
+		for _, dp := range e.Description {
+			if dp.SpecialTag != nil {
+				f.Tags++
+			}
+		}
+		f.Contexts = e.Contexts()
+		got = append(got, f)
+	}
+	want := []fields{
+		{Description: "x marks the spot"},
+		{Description: "2023-05-01 retro notes"},
+		{Completed: true, Description: "mix at ratio 3:1 @garage"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("importCSV read the description as todo.txt (-got,+want):\n%s", diff)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// runExport writes the formatted file in a format for other tools, keeping
+// only the entries that match the filter flags.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "-", "todo.txt file path to export")
+	format := fs.String("format", "md", "Output format, md, csv, or taskwarrior")
+	output := fs.String("o", "-", "File to write to")
+	var filter entryFilter
+	fs.StringVar(&filter.header, "header", "", "Only export entries under this header")
+	fs.StringVar(&filter.project, "project", "", "Only export entries with this +project")
+	fs.StringVar(&filter.context, "context", "", "Only export entries with this @context")
+	fs.BoolVar(&filter.thisWeek, "this-week", false, "Only export entries completed this week")
+	tags := fs.String("tags", "code", "How to write tags in markdown, code or strip")
+	fs.Parse(args)
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	t, err := loadTodoTxt(*filename, now)
+	if err != nil {
+		return err
+	}
+	t = filterTodoTxt(t, filter.matcher(now))
+
+	var buf bytes.Buffer
+	switch *format {
+	case "md":
+		if *tags != "code" && *tags != "strip" {
+			return fmt.Errorf("unknown -tags %q, want code or strip", *tags)
+		}
+		err = exportMarkdown(&buf, t, *tags == "strip")
+	case "csv":
+		err = exportCSV(&buf, t)
+	case "taskwarrior":
+		err = exportTaskwarrior(&buf, t, now)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	if *output == "-" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return writeFileAtomic(*output, buf.Bytes())
+}
+
+// entryFilter picks the entries to export. Empty fields match everything.
+type entryFilter struct {
+	header   string
+	project  string
+	context  string
+	thisWeek bool
+}
+
+func (f entryFilter) matcher(now time.Time) func(heading string, e *ast.Entry) bool {
+	weekStart := dates.DateOf(now).StartOfWeek()
+	return func(heading string, e *ast.Entry) bool {
+		if heading == "" {
+			heading = "Inbox"
+		}
+		switch {
+		case f.header != "" && !strings.EqualFold(heading, f.header):
+			return false
+		case f.project != "" && !slices.Contains(e.Projects(), strings.TrimPrefix(f.project, "+")):
+			return false
+		case f.context != "" && !slices.Contains(e.Contexts(), strings.TrimPrefix(f.context, "@")):
+			return false
+		case f.thisWeek && (!e.Completed || e.CompletionDate == nil || e.CompletionDate.Before(weekStart)):
+			return false
+		}
+		return true
+	}
+}
+
+// filterTodoTxt returns the entries of t that keep accepts, dropping headers
+// and blocks left empty.
+func filterTodoTxt(t ast.TodoTxt, keep func(heading string, e *ast.Entry) bool) ast.TodoTxt {
+	var result ast.TodoTxt
+	for _, g := range t.Groupings {
+		heading := strings.Join(g.Header, " ")
+		filtered := ast.Grouping{Header: g.Header}
+		for _, b := range g.Blocks {
+			var children []*ast.Entry
+			for _, e := range b.Children {
+				if e != nil && keep(heading, e) {
+					children = append(children, e)
+				}
+			}
+			if len(children) > 0 {
+				filtered.Blocks = append(filtered.Blocks, ast.Block{Children: children})
+			}
+		}
+		if len(filtered.Blocks) > 0 {
+			result.Groupings = append(result.Groupings, filtered)
+		}
+	}
+	return result
+}
+
+// exportMarkdown writes t as GitHub flavored markdown checklists, one per
+// header, with notes as nested bullets. Tags are written as inline code, or
+// left out if stripTags is set.
+func exportMarkdown(out io.Writer, t ast.TodoTxt, stripTags bool) error {
+	for i, g := range t.Groupings {
+		header := strings.Join(g.Header, " ")
+		if header == "" {
+			header = "Inbox"
+		}
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "## %s\n\n", header)
+		for _, b := range g.Blocks {
+			for _, e := range b.Children {
+				check := " "
+				if e.Completed {
+					check = "x"
+				}
+				fmt.Fprintf(out, "- [%s] %s\n", check, markdownDescription(e, stripTags))
+				for _, line := range e.Notes {
+					if len(line.Text) > 0 {
+						fmt.Fprintf(out, "  - %s\n", strings.Join(line.Text, " "))
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func markdownDescription(e *ast.Entry, stripTags bool) string {
+	var words []string
+	if e.Priority != nil {
+		words = append(words, *e.Priority)
+	}
+	for _, dp := range e.Description {
+		switch {
+		case dp.Project != nil:
+			words = append(words, "+"+*dp.Project)
+		case dp.Context != nil:
+			words = append(words, "@"+*dp.Context)
+		case dp.SpecialTag != nil:
+			if !stripTags {
+				words = append(words, "`"+dp.SpecialTag.Key+":"+dp.SpecialTag.Value+"`")
+			}
+		default:
+			words = append(words, dp.Text...)
+		}
+	}
+	return strings.Join(words, " ")
+}
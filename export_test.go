@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportMarkdown(t *testing.T) {
+	input := strings.Join([]string{
+		"# Next",
+		"",
+		"  2022-01-01 (A) write report +work @desk due:2022-01-07",
+		"           | ask Sam for numbers",
+		"  2022-01-01 water the plants",
+		"",
+		"# Logged",
+		"",
+		"x 2022-01-04 2022-01-01 send invoices +work",
+		"x 2022-01-03 2022-01-01 book flights +travel",
+		"x 2021-12-31 2021-12-01 close the year +work",
+	}, "\n")
+	now := time.Date(2022, time.January, 05, 0, 0, 0, 0, time.UTC)
+	todo, err := parseTodoTxt([]byte(input), now)
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		filter    entryFilter
+		stripTags bool
+		want      []string
+	}{{
+		name:   "project",
+		filter: entryFilter{project: "+work"},
+		want: []string{
+			"## Next",
+			"",
+			"- [ ] (A) write report +work @desk `due:2022-01-07`",
+			"  - ask Sam for numbers",
+			"",
+			"## Logged",
+			"",
+			"- [x] send invoices +work",
+			"- [x] close the year +work",
+		},
+	}, {
+		name:      "this week",
+		filter:    entryFilter{header: "logged", thisWeek: true},
+		stripTags: true,
+		want: []string{
+			"## Logged",
+			"",
+			"- [x] send invoices +work",
+			"- [x] book flights +travel",
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := exportMarkdown(&buf, filterTodoTxt(todo, tc.filter.matcher(now)), tc.stripTags); err != nil {
+				t.Fatalf("exportMarkdown failed: %v", err)
+			}
+			if diff := cmp.Diff(buf.String(), strings.Join(tc.want, "\n")+"\n"); diff != "" {
+				t.Errorf("exportMarkdown returned unexpected result (-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+// includeDirective turns a heading into an include of another todo file, as
+// in "#include team.txt". Paths are relative to the including file.
+const includeDirective = "include"
+
+// todoFile is one of a set of todo files that include each other.
+type todoFile struct {
+	Name     string
+	Includes []string
+	Tree     ast.TodoTxt
+
+	original []byte
+}
+
+// Key is the name other files use to send entries here with a file: tag,
+// which is the file name up to the first dot.
+func (f *todoFile) Key() string {
+	key, _, _ := strings.Cut(filepath.Base(f.Name), ".")
+	return key
+}
+
+// DumpText writes the include directives followed by the entries.
+func (f *todoFile) DumpText(out io.Writer) error {
+	return dumpWithIncludes(out, f.Includes, f.Tree)
+}
+
+// write formats the file back to disk, if it changed.
+func (f *todoFile) write() error {
+	contents, changed, err := f.format()
+	if err != nil || !changed {
+		return err
+	}
+	if err := writeFileAtomic(f.Name, contents); err != nil {
+		return err
+	}
+	f.original = contents
+	return nil
+}
+
+// format returns the file as it is written, and whether that changed it.
+func (f *todoFile) format() ([]byte, bool, error) {
+	var buf bytes.Buffer
+	if err := f.DumpText(&buf); err != nil {
+		return nil, false, fmt.Errorf("unable to format %s: %w", f.Name, err)
+	}
+	if bytes.Equal(buf.Bytes(), f.original) {
+		return nil, false, nil
+	}
+	if f.Name == "-" {
+		return nil, false, fmt.Errorf("cannot write back to stdin")
+	}
+	return buf.Bytes(), true, nil
+}
+
+// writeFiles formats the files that changed back to disk. Every file is
+// written out before any is replaced, so that an entry moved between files
+// is not lost from one when the other cannot be written.
+func writeFiles(files []*todoFile) error {
+	type staged struct {
+		f        *todoFile
+		tmp      string
+		contents []byte
+	}
+	var writes []staged
+	defer func() {
+		for _, w := range writes {
+			os.Remove(w.tmp) // Fails harmlessly once renamed.
+		}
+	}()
+	for _, f := range files {
+		contents, changed, err := f.format()
+		if err != nil {
+			return err
+		}
+		if !changed {
+			continue
+		}
+		tmp, err := stageFile(f.Name, contents)
+		if err != nil {
+			return err
+		}
+		writes = append(writes, staged{f: f, tmp: tmp, contents: contents})
+	}
+	for _, w := range writes {
+		if err := os.Rename(w.tmp, w.f.Name); err != nil {
+			return err
+		}
+		w.f.original = w.contents
+	}
+	return nil
+}
+
+func dumpWithIncludes(out io.Writer, includes []string, t ast.TodoTxt) error {
+	for _, include := range includes {
+		fmt.Fprintf(out, "#%s %s\n", includeDirective, include)
+	}
+	if len(includes) > 0 && slices.ContainsFunc(t.Groupings, func(g ast.Grouping) bool { return g.Len() > 0 }) {
+		fmt.Fprintln(out)
+	}
+	return t.DumpText(out)
+}
+
+// cutIncludes removes the include directives from a parsed file and returns
+// the paths they name. Entries written under an include directive go to the
+// Inbox.
+func cutIncludes(t *ast.TodoTxt) []string {
+	var includes []string
+	kept := make([]ast.Grouping, 0, len(t.Groupings))
+	for _, g := range t.Groupings {
+		if isInclude(g) {
+			includes = append(includes, g.Header[1])
+			if g.Len() == 0 {
+				continue
+			}
+			g.Header = nil
+		}
+		kept = append(kept, g)
+	}
+	t.Groupings = kept
+	return includes
+}
+
+// isInclude reports whether a grouping was read from an include directive.
+// Only "#include path" counts; a header like "# include notes" is a header.
+func isInclude(g ast.Grouping) bool {
+	words := strings.Fields(g.Source)
+	return len(g.Header) == 2 && len(words) == 2 && words[0] == "#"+includeDirective
+}
+
+// loadTodoFile reads and parses a single todo file, leaving the files it
+// includes alone.
+func loadTodoFile(name string) (*todoFile, error) {
+	input, err := readInput(name)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := parseFile(name, input)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &todoFile{
+		Name:     name,
+		Includes: cutIncludes(&tree),
+		Tree:     tree,
+		original: input,
+	}, nil
+}
+
+// loadTodoFiles reads and parses filename and every file it includes,
+// depth first. A file included twice is only read once.
+func loadTodoFiles(filename string) ([]*todoFile, error) {
+	var files []*todoFile
+	seen := make(map[string]bool)
+	var load func(name string) error
+	load = func(name string) error {
+		key := name
+		if name != "-" {
+			var err error
+			if key, err = filepath.Abs(name); err != nil {
+				return err
+			}
+		}
+		if seen[key] {
+			return nil
+		}
+		seen[key] = true
+
+		f, err := loadTodoFile(name)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+
+		dir := "."
+		if name != "-" {
+			dir = filepath.Dir(name)
+		}
+		for _, include := range f.Includes {
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(dir, include)
+			}
+			if err := load(include); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return files, load(filename)
+}
+
+// routeEntries moves entries tagged file:<key> into the file with that key,
+// under the same header. It returns the keys that did not match any file;
+// those entries stay where they are.
+func routeEntries(files []*todoFile) []string {
+	byKey := make(map[string]*todoFile)
+	for _, f := range files {
+		if _, ok := byKey[f.Key()]; !ok {
+			byKey[f.Key()] = f
+		}
+	}
+
+	var unknown []string
+	for _, f := range files {
+		for gi := range f.Tree.Groupings {
+			g := &f.Tree.Groupings[gi]
+			for bi := range g.Blocks {
+				ast.SliceRemove(&g.Blocks[bi].Children, func(e *ast.Entry) bool {
+					key, ok := e.Tag("file")
+					if !ok {
+						return false
+					}
+					dst, ok := byKey[key]
+					if !ok {
+						unknown = append(unknown, key)
+						return false
+					}
+					e.RemoveTag("file")
+					if dst == f {
+						return false
+					}
+					dst.Tree.Groupings = append(dst.Tree.Groupings, ast.Grouping{
+						Header: g.Header,
+						Blocks: []ast.Block{{Children: []*ast.Entry{e}}},
+					})
+					return true
+				})
+			}
+		}
+	}
+	return unknown
+}
+
+func compileFiles(files []*todoFile, now time.Time) {
+	for _, f := range files {
+		f.Tree = compileTodoTxt(f.Tree, now)
+	}
+}
+
+// mergeFiles combines already compiled files into one document for reading,
+// with one grouping per header.
+func mergeFiles(files []*todoFile) ast.TodoTxt {
+	var result ast.TodoTxt
+	index := make(map[string]int)
+	for _, f := range files {
+		for _, g := range f.Tree.Groupings {
+			name := strings.Join(g.Header, " ")
+			if i, ok := index[name]; ok {
+				result.Groupings[i].Blocks = append(result.Groupings[i].Blocks, g.Blocks...)
+				continue
+			}
+			index[name] = len(result.Groupings)
+			result.Groupings = append(result.Groupings, ast.Grouping{
+				Header: g.Header,
+				Blocks: slices.Clone(g.Blocks),
+				Source: g.Source,
+			})
+		}
+	}
+	sortGroupings(result.Groupings, slices.Clone(result.Groupings))
+	return result
+}
+
+// runSync formats a todo file and every file it includes in place, moving
+// entries between them according to their file: tags.
+func runSync(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "todo.txt", "Top level todo.txt file path to sync")
+	fs.Parse(args)
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	files, err := loadTodoFiles(*filename)
+	if err != nil {
+		return err
+	}
+	for _, key := range routeEntries(files) {
+		fmt.Fprintf(os.Stderr, "no included file for file:%s\n", key)
+	}
+	compileFiles(files, now)
+	return writeFiles(files)
+}
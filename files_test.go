@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSyncFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, lines ...string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("todo.txt",
+		"#include shared/team.txt",
+		"#include todo.txt",
+		"",
+		"# Next",
+		"",
+		"  2022-01-01 fix the printer file:team",
+		"  2022-01-01 water the plants",
+	)
+	write("shared/team.txt",
+		"<!-- Shared with the team. -->",
+		"",
+		"# Inbox",
+		"",
+		"  2022-01-01 order lunch +party",
+		"  2022-01-01 book my flight file:todo",
+	)
+
+	files, err := loadTodoFiles(filepath.Join(dir, "todo.txt"))
+	if err != nil {
+		t.Fatalf("loadTodoFiles failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("want 2 files, got %d", len(files))
+	}
+	if unknown := routeEntries(files); len(unknown) != 0 {
+		t.Errorf("unexpected unknown files %v", unknown)
+	}
+	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
+	compileFiles(files, now)
+
+	merged := mergeFiles(files)
+	for _, header := range []string{"Inbox", "Next"} {
+		if g := findGrouping(&merged, header); g == nil || g.Len() != 2 {
+			t.Errorf("merged files should have 2 entries in %s, got %+v", header, merged.Groupings)
+		}
+	}
+
+	for _, f := range files {
+		if err := f.write(); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	want := map[string]string{
+		"todo.txt": strings.Join([]string{
+			"#include shared/team.txt",
+			"#include todo.txt",
+			"",
+			"# Inbox",
+			"",
+			"  2022-01-01 book my flight",
+			"",
+			"# Next",
+			"",
+			"  2022-01-01 water the plants",
+		}, "\n") + "\n",
+		"shared/team.txt": strings.Join([]string{
+			"<!-- Shared with the team. -->",
+			"",
+			"# Inbox",
+			"",
+			"  2022-01-01 order lunch +party",
+			"",
+			"# Next",
+			"",
+			"  2022-01-01 fix the printer",
+		}, "\n") + "\n",
+	}
+	for name, contents := range want {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(string(got), contents); diff != "" {
+			t.Errorf("%s has unexpected contents (-got,+want):\n%s", name, diff)
+		}
+	}
+}
+
+func TestSyncFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	top := "#include shared/team.txt\n\n# Next\n\n  2022-01-01 fix the printer file:team\n"
+	if err := os.WriteFile(filepath.Join(dir, "todo.txt"), []byte(top), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(dir, "shared"), 0755)
+	if err := os.WriteFile(filepath.Join(dir, "shared/team.txt"), []byte("# Next\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := loadTodoFiles(filepath.Join(dir, "todo.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	routeEntries(files)
+	compileFiles(files, time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC))
+	// The included file can no longer be written.
+	if err := os.RemoveAll(filepath.Join(dir, "shared")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFiles(files); err == nil {
+		t.Fatal("writeFiles succeeded, want an error")
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "todo.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(got), top); diff != "" {
+		t.Errorf("todo.txt changed (-got,+want):\n%s", diff)
+	}
+}
+
+func TestCutIncludes(t *testing.T) {
+	tree, err := parseFile("", []byte("#include team.txt\n# include notes\n\n  2022-01-01 read the notes\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(cutIncludes(&tree), []string{"team.txt"}); diff != "" {
+		t.Errorf("unexpected includes (-got,+want):\n%s", diff)
+	}
+	if len(tree.Groupings) != 1 || strings.Join(tree.Groupings[0].Header, " ") != "include notes" {
+		t.Errorf("want the include notes header kept, got %+v", tree.Groupings)
+	}
+}
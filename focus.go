@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+// focusHeaders are the headers focus mode shows.
+var focusHeaders = []string{"Today", "Next"}
+
+// focusStateSuffix names the file next to a focus buffer that remembers
+// where the buffer came from, for merging it back.
+const focusStateSuffix = ".focus"
+
+// energyLevels orders the values of energy: tags.
+var energyLevels = map[string]int{
+	"low":    1,
+	"med":    2,
+	"medium": 2,
+	"high":   3,
+}
+
+// focusFilter picks the open tasks to focus on. Tasks that say nothing
+// about what a filter asks for, like tasks without a context, always pass.
+type focusFilter struct {
+	// Contexts keeps the tasks with one of these contexts, if any are
+	// given.
+	Contexts []string `json:"contexts,omitempty"`
+	// Not leaves out the tasks with any of these contexts.
+	Not []string `json:"not,omitempty"`
+	// Energy leaves out the tasks whose energy: tag asks for more.
+	Energy string `json:"energy,omitempty"`
+	// Time leaves out the tasks estimated to take longer by their est: tag,
+	// like est:30m.
+	Time time.Duration `json:"time,omitempty"`
+}
+
+func (f focusFilter) Match(e *ast.Entry) bool {
+	if e.Completed {
+		return false
+	}
+	contexts := e.Contexts()
+	if len(f.Contexts) > 0 && len(contexts) > 0 && !slices.ContainsFunc(contexts, func(c string) bool {
+		return slices.Contains(f.Contexts, c)
+	}) {
+		return false
+	}
+	if slices.ContainsFunc(contexts, func(c string) bool { return slices.Contains(f.Not, c) }) {
+		return false
+	}
+	if energy, ok := e.Tag("energy"); ok && f.Energy != "" {
+		if level, ok := energyLevels[strings.ToLower(energy)]; ok && level > energyLevels[f.Energy] {
+			return false
+		}
+	}
+	if est, ok := e.Tag("est"); ok && f.Time > 0 {
+		if d, err := time.ParseDuration(est); err == nil && d > f.Time {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the filter for the top of a focus buffer.
+func (f focusFilter) String() string {
+	var parts []string
+	for _, c := range f.Contexts {
+		parts = append(parts, "@"+c)
+	}
+	for _, c := range f.Not {
+		parts = append(parts, "not @"+c)
+	}
+	if f.Energy != "" {
+		parts = append(parts, f.Energy+" energy")
+	}
+	if f.Time > 0 {
+		parts = append(parts, "at most "+f.Time.String())
+	}
+	if len(parts) == 0 {
+		return "everything"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// focusView returns the tasks in the focus headers of a formatted file that
+// match filter, under the same headers.
+func focusView(t ast.TodoTxt, filter focusFilter) ast.TodoTxt {
+	var view ast.TodoTxt
+	for _, header := range focusHeaders {
+		g := ast.Grouping{Header: []string{header}, Blocks: []ast.Block{{}}}
+		for _, e := range findEntries(&t, func(heading string, e *ast.Entry) bool {
+			return heading == header && e != nil && filter.Match(e)
+		}) {
+			g.Blocks[0].Children = append(g.Blocks[0].Children, *e)
+		}
+		if g.Len() > 0 {
+			view.Groupings = append(view.Groupings, g)
+		}
+	}
+	return view
+}
+
+// focusState is what a focus buffer was made from.
+type focusState struct {
+	File   string      `json:"file"`
+	Filter focusFilter `json:"filter"`
+	// Base is the buffer as written, to tell what was edited since.
+	Base string `json:"base"`
+}
+
+// runFocus prints the tasks in Today and Next that can be done in a context,
+// or writes them to a buffer to edit and merge back.
+func runFocus(args []string) error {
+	fs := flag.NewFlagSet("focus", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "todo.txt", "todo.txt file path to focus on")
+	not := fs.String("not", "", "Comma separated contexts to leave out")
+	energy := fs.String("energy", "", "Leave out tasks whose energy: tag is higher than this, low, medium, or high")
+	within := fs.Duration("time", 0, "Leave out tasks whose est: tag is longer than this")
+	output := fs.String("o", "", "Write the tasks to this file to edit, instead of printing them")
+	merge := fs.String("merge", "", "Merge the edits to a file written with -o back into the file it came from")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vogon focus [flags] [@context ...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	if *merge != "" {
+		return mergeFocus(*merge, now)
+	}
+
+	filter := focusFilter{Energy: strings.ToLower(*energy), Time: *within}
+	for _, c := range fs.Args() {
+		filter.Contexts = append(filter.Contexts, strings.TrimPrefix(c, "@"))
+	}
+	for _, c := range strings.Split(*not, ",") {
+		if c = strings.TrimPrefix(strings.TrimSpace(c), "@"); c != "" {
+			filter.Not = append(filter.Not, c)
+		}
+	}
+	if _, ok := energyLevels[filter.Energy]; filter.Energy != "" && !ok {
+		return fmt.Errorf("unknown energy %q, want low, medium, or high", *energy)
+	}
+
+	if *output == "" {
+		t, err := loadTodoTxt(*filename, now)
+		if err != nil {
+			return err
+		}
+		return focusView(t, filter).DumpText(os.Stdout)
+	}
+	if *filename == "-" {
+		return fmt.Errorf("cannot merge a focus buffer back into stdin")
+	}
+	source, err := filepath.Abs(*filename)
+	if err != nil {
+		return err
+	}
+	f, err := loadTodoFile(source)
+	if err != nil {
+		return err
+	}
+	f.Tree = compileTodoTxt(f.Tree, now)
+	return writeFocus(*output, focusState{File: source, Filter: filter}, f.Tree)
+}
+
+// writeFocus writes the focus view of t to a buffer, along with its state.
+func writeFocus(buffer string, state focusState, t ast.TodoTxt) error {
+	var view strings.Builder
+	if err := focusView(t, state.Filter).DumpText(&view); err != nil {
+		return err
+	}
+	state.Base = view.String()
+	contents := fmt.Sprintf("<!-- Focus on %s from %s. Saving merges the changes back. -->\n\n%s",
+		state.Filter, state.File, state.Base)
+	if err := writeFileAtomic(buffer, []byte(contents)); err != nil {
+		return err
+	}
+	encoded, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(buffer+focusStateSuffix, encoded)
+}
+
+// mergeFocus merges the edits to a focus buffer back into the file it came
+// from, then writes the buffer again from the merged file.
+func mergeFocus(buffer string, now time.Time) error {
+	encoded, err := os.ReadFile(buffer + focusStateSuffix)
+	if err != nil {
+		return fmt.Errorf("%s was not written by vogon focus -o: %w", buffer, err)
+	}
+	var state focusState
+	if err := json.Unmarshal(encoded, &state); err != nil {
+		return fmt.Errorf("%s: %w", buffer+focusStateSuffix, err)
+	}
+	base, err := parseFile(buffer, []byte(state.Base))
+	if err != nil {
+		return err
+	}
+	input, err := readInput(buffer)
+	if err != nil {
+		return err
+	}
+	edited, err := parseFile(buffer, input)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(state.File)
+	if errors.Is(err, errLocked) {
+		return fmt.Errorf("%s is being written, try again", state.File)
+	} else if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := loadTodoFile(state.File)
+	if err != nil {
+		return err
+	}
+	f.Tree = compileTodoTxt(f.Tree, now)
+	conflicts := applyFocusEdits(&f.Tree, base, edited)
+	f.Tree = compileTodoTxt(f.Tree, now)
+	if err := f.write(); err != nil {
+		return err
+	}
+	if err := writeFocus(buffer, state, f.Tree); err != nil {
+		return err
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d conflicting entries, tagged %s:ours and %s:theirs", conflicts, conflictTag, conflictTag)
+	}
+	return nil
+}
+
+// applyFocusEdits makes the changes from base to edited in t, which is the
+// file base was taken from and may have changed since. Entries of the buffer
+// are matched and merged the same way as by vogon merge, with t as ours and
+// edited as theirs, but the rest of t is left as it is.
+func applyFocusEdits(t *ast.TodoTxt, base, edited ast.TodoTxt) int {
+	baseKeys, baseEntries := indexEntries(base)
+	editedKeys, editedEntries := indexEntries(edited)
+	oursEntries := matchFocusEntries(t, baseKeys, baseEntries)
+
+	conflicts := 0
+	conflict := func(o, e *mergeEntry) {
+		conflicts++
+		if o != nil {
+			o.Entry.Description = append(o.Entry.Description, &ast.DescriptionPart{
+				SpecialTag: &ast.SpecialTag{Key: conflictTag, Value: "ours"},
+			})
+		}
+		e.Entry.Description = append(e.Entry.Description, &ast.DescriptionPart{
+			SpecialTag: &ast.SpecialTag{Key: conflictTag, Value: "theirs"},
+		})
+		moveEntry(t, e.Entry, strings.Join(e.Header, " "))
+	}
+
+	for _, key := range editedKeys {
+		b, inBase := baseEntries[key]
+		o, inOurs := oursEntries[key]
+		e := editedEntries[key]
+		switch {
+		case inOurs:
+			m, ok := merge3(b, inBase, o, e)
+			if !ok {
+				conflict(&o, &e)
+				continue
+			}
+			if m.Entry != o.Entry {
+				*o.Entry = *m.Entry
+			}
+			if !sameHeader(m.Header, o.Header) {
+				moveEntry(t, o.Entry, strings.Join(m.Header, " "))
+			}
+		case !inBase:
+			moveEntry(t, e.Entry, strings.Join(e.Header, " ")) // Added in the buffer.
+		case e.text != b.text:
+			conflict(nil, &e) // Removed from the file, changed in the buffer.
+		}
+	}
+	for key, b := range baseEntries {
+		if _, ok := editedEntries[key]; ok {
+			continue
+		}
+		// Removed in the buffer, and so from the file unless it was changed
+		// there since.
+		if o, ok := oursEntries[key]; ok && o.text == b.text {
+			removeEntry(t, o.Entry)
+		}
+	}
+	return conflicts
+}
+
+// matchFocusEntries finds the entries of t that the entries of a focus buffer
+// were taken from, by the keys of base. An entry is found by its id tag, or
+// else by being written the same under the same header, in order, or else by
+// being the only one under the same header with the same words, since it may
+// have been edited in the file too. Other than by id, entries under other
+// headers are never taken for ones in the buffer.
+func matchFocusEntries(t *ast.TodoTxt, keys []string, base map[string]mergeEntry) map[string]mergeEntry {
+	var entries []mergeEntry
+	for _, g := range t.Groupings {
+		for bi, b := range g.Blocks {
+			for _, e := range b.Children {
+				if e != nil {
+					entries = append(entries, mergeEntry{Header: g.Header, Entry: e, text: mergeText(e), block: bi})
+				}
+			}
+		}
+	}
+
+	matched := make(map[string]mergeEntry)
+	used := make(map[*ast.Entry]bool)
+	match := func(same func(b, o mergeEntry) bool, unique bool) {
+		for _, key := range keys {
+			if _, ok := matched[key]; ok {
+				continue
+			}
+			b := base[key]
+			var found []mergeEntry
+			for _, o := range entries {
+				if !used[o.Entry] && same(b, o) {
+					found = append(found, o)
+				}
+			}
+			if len(found) == 0 || (unique && len(found) > 1) {
+				continue
+			}
+			matched[key] = found[0]
+			used[found[0].Entry] = true
+		}
+	}
+	match(func(b, o mergeEntry) bool {
+		id, ok := b.Entry.Tag("id")
+		other, otherOK := o.Entry.Tag("id")
+		return ok && otherOK && id == other
+	}, true)
+	match(func(b, o mergeEntry) bool {
+		return sameHeader(b.Header, o.Header) && b.text == o.text
+	}, false)
+	match(func(b, o mergeEntry) bool {
+		return sameHeader(b.Header, o.Header) && entryKey(b.Entry) == entryKey(o.Entry)
+	}, true)
+	return matched
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFocusView(t *testing.T) {
+	now := time.Date(2024, time.May, 6, 9, 0, 0, 0, time.UTC)
+	todo, err := parseTodoTxt([]byte(`# Today
+
+  2024-05-01 call mom @phone
+  2024-05-01 write report @office est:2h
+  2024-05-01 water plants
+x 2024-05-06 2024-05-01 pay rent @office
+
+# Next
+
+  2024-05-01 file expenses @office energy:low est:15m
+  2024-05-01 clean garage @home energy:high
+  2024-05-01 plan offsite @office energy:high
+
+# Someday
+
+  2024-05-01 learn piano @home
+`), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := []struct {
+		name   string
+		filter focusFilter
+		want   string
+	}{{
+		name:   "context",
+		filter: focusFilter{Contexts: []string{"office"}},
+		want: `# Today
+
+  2024-05-01 write report @office est:2h
+  2024-05-01 water plants
+
+# Next
+
+  2024-05-01 file expenses @office energy:low est:15m
+  2024-05-01 plan offsite @office energy:high
+`,
+	}, {
+		name:   "not",
+		filter: focusFilter{Not: []string{"office", "phone"}},
+		want: `# Today
+
+  2024-05-01 water plants
+
+# Next
+
+  2024-05-01 clean garage @home energy:high
+`,
+	}, {
+		name:   "energy and time",
+		filter: focusFilter{Energy: "medium", Time: time.Hour},
+		want: `# Today
+
+  2024-05-01 call mom @phone
+  2024-05-01 water plants
+
+# Next
+
+  2024-05-01 file expenses @office energy:low est:15m
+`,
+	}, {
+		name:   "nothing",
+		filter: focusFilter{Contexts: []string{"garden"}, Not: []string{"garden"}, Energy: "low"},
+		want: `# Today
+
+  2024-05-01 water plants
+`,
+	}}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			var got strings.Builder
+			if err := focusView(todo, tc.filter).DumpText(&got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got.String(), tc.want); diff != "" {
+				t.Errorf("unexpected view (-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFocusMerge(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.txt")
+	buffer := filepath.Join(dir, "focus.txt")
+	write := func(name, contents string) {
+		if err := os.WriteFile(name, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(got)
+	}
+	replace := func(name, old, new string) {
+		if contents := read(name); strings.Contains(contents, old) {
+			write(name, strings.Replace(contents, old, new, 1))
+		} else {
+			t.Fatalf("%s does not have %q:\n%s", name, old, contents)
+		}
+	}
+
+	write(filename, `<!-- At home and at work. -->
+
+# Today
+
+  2024-05-01 call mom @phone
+  2024-05-01 write report @office est:2h
+  2024-05-01 water plants
+
+# Next
+
+  2024-05-01 file expenses @office
+  2024-05-01 clean garage @home
+
+> Notes on the garage.
+`)
+	if err := runFocus([]string{"-f", filename, "-now", "2024-05-06", "-o", buffer, "office"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(read(buffer), "<!-- Focus on @office from "+filename) {
+		t.Errorf("unexpected buffer:\n%s", read(buffer))
+	}
+
+	replace(buffer, "  2024-05-01 water plants", "x 2024-05-06 2024-05-01 water plants")
+	replace(buffer, "est:2h", "est:3h")
+	replace(buffer, "  2024-05-01 file expenses @office\n", "")
+	write(buffer, read(buffer)+"  book the meeting room @office\n")
+	// The file changes while the buffer is open.
+	replace(filename, "call mom", "call mom +family")
+	if err := runFocus([]string{"-now", "2024-05-06", "-merge", buffer}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<!-- At home and at work. -->
+
+# Today
+
+  2024-05-01 call mom +family @phone
+  2024-05-01 write report @office est:3h
+
+# Next
+
+  2024-05-06 book the meeting room @office
+  2024-05-01 clean garage @home
+
+> Notes on the garage.
+
+# Logged
+
+x 2024-05-06 2024-05-01 water plants
+`
+	if diff := cmp.Diff(read(filename), want); diff != "" {
+		t.Errorf("unexpected file (-got,+want):\n%s", diff)
+	}
+	if !strings.HasSuffix(read(buffer), "# Next\n\n  2024-05-06 book the meeting room @office\n") {
+		t.Errorf("buffer was not written again from the file:\n%s", read(buffer))
+	}
+
+	// Both changed the same task.
+	replace(buffer, "est:3h", "est:4h")
+	replace(filename, "est:3h", "est:1h")
+	if err := runFocus([]string{"-now", "2024-05-06", "-merge", buffer}); err == nil {
+		t.Errorf("merging a conflict succeeded, want an error")
+	}
+	if got := read(filename); !strings.Contains(got, "est:1h conflict:ours") || !strings.Contains(got, "est:4h conflict:theirs") {
+		t.Errorf("conflict was not tagged:\n%s", got)
+	}
+}
+
+func TestFocusMergeSameWords(t *testing.T) {
+	defer func(stale int) { *staleDays = stale }(*staleDays)
+	*staleDays = 14
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.txt")
+	buffer := filepath.Join(dir, "focus.txt")
+	input := `# Inbox
+
+  2021-12-01 call mom age:30d
+
+# Today
+
+  2021-12-31 call mom @phone
+`
+	if err := os.WriteFile(filename, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runFocus([]string{"-f", filename, "-now", "2022-01-01", "-o", buffer}); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(contents), "  2021-12-31 call mom", "x 2022-01-01 2021-12-31 call mom", 1)
+	if err := os.WriteFile(buffer, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runFocus([]string{"-now", "2022-01-01", "-merge", buffer}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Inbox
+
+  2021-12-01 call mom age:31d
+
+# Logged
+
+x 2022-01-01 2021-12-31 call mom @phone
+`
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("unexpected file (-got,+want):\n%s", diff)
+	}
+}
@@ -10,12 +10,64 @@ setlocal textwidth=0
 set autoread
 autocmd BufWritePre todo.txt call TodoTxtFmt()
 
+" Extra flags for the formatter, e.g. let g:vogon_flags = '-stale 14'
+if !exists('g:vogon_flags')
+  let g:vogon_flags = ''
+endif
+
+" Set g:vogon_watch when running vogon watch on the file, so that it waits
+" for vim to finish saving before formatting.
+if get(g:, 'vogon_watch', 0)
+  autocmd BufWritePre todo.txt call writefile([], expand('<afile>:p') . '.lock')
+  autocmd BufWritePost todo.txt call delete(expand('<afile>:p') . '.lock')
+endif
+
+" Formatting records what it changed in todo.txt.journal next to the file,
+" for vogon undo. Set g:vogon_journal = 0 to turn that off.
+if !exists('g:vogon_journal')
+  let g:vogon_journal = 1
+endif
+
 function! TodoTxtFmt() abort
 let l:curw = winsaveview()
-%!vogon -f -
+let l:flags = g:vogon_flags
+if g:vogon_journal
+  let l:flags .= ' -journal ' . shellescape(expand('%:p') . '.journal', 1)
+endif
+execute '%!vogon ' . l:flags . ' -f -'
 call winrestview(l:curw)
 endfunction
 
+" :Focus @office opens the open tasks in Today and Next for a context, and
+" merges the edits back into the file whenever the buffer is saved. It takes
+" the same arguments as vogon focus.
+command! -buffer -nargs=* Focus call TodoTxtFocus(<q-args>)
+
+function! TodoTxtFocus(args) abort
+let l:buffer = tempname() . '.txt'
+let l:out = system('vogon focus -f ' . shellescape(expand('%:p')) . ' -o ' . shellescape(l:buffer) . ' ' . a:args)
+if v:shell_error
+  echoerr l:out
+  return
+endif
+execute 'split ' . fnameescape(l:buffer)
+setlocal filetype=todotxt
+execute 'autocmd BufWritePost <buffer> call TodoTxtFocusMerge(' . string(l:buffer) . ')'
+endfunction
+
+function! TodoTxtFocusMerge(buffer) abort
+let l:out = system('vogon focus -merge ' . shellescape(a:buffer))
+if v:shell_error
+  echohl WarningMsg | echo l:out | echohl None
+endif
+silent edit!
+checktime
+endfunction
+
 " Set a pipe character with space following as a comment,
 " which allows for easier note writing.
 setlocal comments+=b:\|
+
+" :make lists dates vogon cannot make sense of in the quickfix list.
+setlocal makeprg=vogon\ check\ -f\ %
+setlocal errorformat=%f:%l:%c:\ %m,%-G%.%#
@@ -4,4 +4,4 @@ go 1.18
 
 require github.com/alecthomas/participle/v2 v2.0.0-alpha8
 
-require github.com/google/go-cmp v0.5.8 // indirect
+require github.com/google/go-cmp v0.5.8
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+// importedEntry is an entry read from another tool, along with the header it
+// was under there, if any.
+type importedEntry struct {
+	Header string
+	Entry  *ast.Entry
+}
+
+// runImport adds entries from another tool's file to a todo file. They are
+// formatted along with the rest, so they land under the headers their dates
+// and tags call for.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "todo.txt", "todo.txt file path to add entries to")
+	format := fs.String("format", "csv", "Input format, csv or taskwarrior")
+	columns := fs.String("map", "", "Comma separated field=Column pairs naming the CSV columns to read, like description=Title,due=Due")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vogon import [flags] [file]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	source := "-"
+	if fs.NArg() > 0 {
+		source = fs.Arg(0)
+	}
+	input, err := readInput(source)
+	if err != nil {
+		return err
+	}
+
+	var entries []importedEntry
+	switch *format {
+	case "csv":
+		mapping, err := parseColumnMap(*columns)
+		if err != nil {
+			return err
+		}
+		entries, err = importCSV(bytes.NewReader(input), mapping)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	case "taskwarrior":
+		entries, err = importTaskwarrior(bytes.NewReader(input), now.Location())
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	f, err := loadTodoFile(*filename)
+	if err != nil {
+		return err
+	}
+	addImported(&f.Tree, entries)
+	f.Tree = compileTodoTxt(f.Tree, now)
+	if err := f.write(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "imported %d entries into %s\n", len(entries), *filename)
+	return nil
+}
+
+// addImported adds entries to t under their headers, or the Inbox if they
+// have none.
+func addImported(t *ast.TodoTxt, entries []importedEntry) {
+	for _, imported := range entries {
+		moveEntry(t, imported.Entry, imported.Header)
+	}
+}
+
+// importedDescription starts the description of an entry read from another
+// tool. The words are kept as text, even where they look like todo.txt
+// syntax, such as a date, a leading x, or a key:value tag.
+func importedDescription(text string) ([]*ast.DescriptionPart, error) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("missing description")
+	}
+	return []*ast.DescriptionPart{{Text: words}}, nil
+}
+
+// addProject, addContext, and addTag add to the description of an entry read
+// from another tool.
+func addProject(e *ast.Entry, project string) {
+	e.Description = append(e.Description, &ast.DescriptionPart{Project: &project})
+}
+
+func addContext(e *ast.Entry, context string) {
+	e.Description = append(e.Description, &ast.DescriptionPart{Context: &context})
+}
+
+func addTag(e *ast.Entry, key, value string) {
+	e.Description = append(e.Description, &ast.DescriptionPart{
+		SpecialTag: &ast.SpecialTag{Key: key, Value: value},
+	})
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+const (
+	// journalSuffix names the file next to a todo file that records what
+	// formatting did to its entries, for vogon undo.
+	journalSuffix = ".journal"
+	// journalLimit is how many runs the journal remembers.
+	journalLimit = 100
+)
+
+var journalFile = flag.String("journal", "", "Record what formatting changes about entries in this file, for vogon undo. Name it only when the output replaces the -f file, like the -f file with "+journalSuffix+" added")
+
+// journalRun is what one run of the formatter did to the entries of a file.
+type journalRun struct {
+	Time    time.Time       `json:"time"`
+	Changes []journalChange `json:"changes"`
+}
+
+// journalChange is what a run did to one entry: the header it was under and
+// its lines, before and after, and a summary for people.
+type journalChange struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+	What   string `json:"what"`
+}
+
+// journalFor returns where the journal for filename is: named, if it is set,
+// or next to the file. Stdin has no journal unless one is named. Commands that
+// write the file themselves keep it there; the formatter, which only prints,
+// keeps one only when it is named.
+func journalFor(filename, named string) string {
+	if named != "" || filename == "-" {
+		return named
+	}
+	return filename + journalSuffix
+}
+
+// entrySnapshot is an entry as it was before formatting, which changes
+// entries in place.
+type entrySnapshot struct {
+	header string
+	text   string
+}
+
+// snapshotEntries records the header and lines of every entry in t.
+func snapshotEntries(t *ast.TodoTxt) map[*ast.Entry]entrySnapshot {
+	snapshot := make(map[*ast.Entry]entrySnapshot)
+	visitAllEntries(t, func(heading string, e *ast.Entry) error {
+		if e == nil {
+			return nil
+		}
+		if heading == "" {
+			heading = "Inbox" // Where entries before any header go.
+		}
+		snapshot[e] = entrySnapshot{header: heading, text: entryLines(e)}
+		return nil
+	})
+	return snapshot
+}
+
+// journalChanges lists the entries of t that moved or were rewritten since
+// snapshot was taken.
+func journalChanges(snapshot map[*ast.Entry]entrySnapshot, t *ast.TodoTxt) []journalChange {
+	var changes []journalChange
+	visitAllEntries(t, func(heading string, e *ast.Entry) error {
+		before, ok := snapshot[e]
+		if !ok {
+			return nil
+		}
+		after := entryLines(e)
+		if before.header == heading && sameWords(before.text, after) {
+			return nil
+		}
+		change := journalChange{From: before.header, To: heading, Before: before.text, After: after}
+		if old, err := parseJournalEntry(before.text); err == nil {
+			change.What = describeChange(change.From, change.To, old, e)
+		}
+		changes = append(changes, change)
+		return nil
+	})
+	return changes
+}
+
+// appendJournal adds a run with changes to the journal at filename, which
+// keeps the last journalLimit runs. Runs that change nothing are left out.
+func appendJournal(filename string, now time.Time, changes []journalChange) error {
+	if filename == "" || len(changes) == 0 {
+		return nil
+	}
+	runs, err := readJournal(filename)
+	if err != nil {
+		return err
+	}
+	runs = append(runs, journalRun{Time: now, Changes: changes})
+	if len(runs) > journalLimit {
+		runs = runs[len(runs)-journalLimit:]
+	}
+	return writeJournal(filename, runs)
+}
+
+// readJournal reads the runs in the journal at filename, oldest first. A
+// journal that does not exist yet has no runs.
+func readJournal(filename string) ([]journalRun, error) {
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []journalRun
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var run journalRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+// writeJournal replaces the journal at filename with runs, one per line.
+func writeJournal(filename string, runs []journalRun) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, run := range runs {
+		if err := enc.Encode(run); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(filename, buf.Bytes())
+}
+
+// runUndo reverses the last n runs of the formatter recorded in the journal,
+// newest first, against the file as it is now. Entries that were edited since
+// keep the edits, and only what the formatter changed and nobody touched
+// after is put back. Entries that cannot be found any more are reported.
+func runUndo(args []string) error {
+	fs := flag.NewFlagSet("undo", flag.ExitOnError)
+	filename := fs.String("f", "todo.txt", "todo.txt file path to undo formatting in")
+	journal := fs.String("journal", "", "Journal to undo from. Defaults to the -f file with "+journalSuffix+" added")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vogon undo [flags] [n]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	n := 1
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("undo takes at most one argument")
+	} else if fs.NArg() == 1 {
+		var err error
+		if n, err = strconv.Atoi(fs.Arg(0)); err != nil || n < 1 {
+			return fmt.Errorf("want a number of runs to undo, got %q", fs.Arg(0))
+		}
+	}
+	if *filename == "-" {
+		return fmt.Errorf("cannot undo formatting of stdin")
+	}
+
+	unlock, err := lockFile(*filename)
+	if errors.Is(err, errLocked) {
+		return fmt.Errorf("%s is being written, try again", *filename)
+	} else if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := journalFor(*filename, *journal)
+	runs, err := readJournal(path)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("nothing to undo in %s", path)
+	}
+	if n > len(runs) {
+		n = len(runs)
+	}
+	f, err := loadTodoFile(*filename)
+	if err != nil {
+		return err
+	}
+
+	var missed, total int
+	for i := len(runs) - 1; i >= len(runs)-n; i-- {
+		// Within a run, each change is to a different entry.
+		used := make(map[*ast.Entry]bool)
+		for j := len(runs[i].Changes) - 1; j >= 0; j-- {
+			c := runs[i].Changes[j]
+			total++
+			if err := undoChange(&f.Tree, c, used); err != nil {
+				fmt.Fprintf(os.Stderr, "cannot undo %s: %v\n", summary(c), err)
+				missed++
+				continue
+			}
+			fmt.Printf("undid %s\n", summary(c))
+		}
+	}
+
+	// Headers that had to be added back go where the formatter puts them.
+	sortGroupings(f.Tree.Groupings, f.Tree.Groupings)
+	if err := f.write(); err != nil {
+		return err
+	}
+	if err := writeJournal(path, runs[:len(runs)-n]); err != nil {
+		return err
+	}
+	if missed > 0 {
+		return fmt.Errorf("could not undo %d of %d changes", missed, total)
+	}
+	return nil
+}
+
+// summary names a change for people.
+func summary(c journalChange) string {
+	words := strings.Join(strings.Fields(c.After), " ")
+	if c.What == "" {
+		return fmt.Sprintf("%q", words)
+	}
+	return fmt.Sprintf("%q (%s)", words, c.What)
+}
+
+// undoChange finds the entry c left behind in t and reverses c. An entry
+// still exactly as the run left it gets its old lines back. Otherwise an
+// entry with the same plain words, whatever its projects, contexts, and tags,
+// gets back whatever the run
+// changed and was not changed again since. The entry moves back only if it
+// is still where the run put it.
+func undoChange(t *ast.TodoTxt, c journalChange, used map[*ast.Entry]bool) error {
+	before, err := parseJournalEntry(c.Before)
+	if err != nil {
+		return err
+	}
+	after, err := parseJournalEntry(c.After)
+	if err != nil {
+		return err
+	}
+
+	var exact, similar []*ast.Entry
+	headings := make(map[*ast.Entry]string)
+	visitAllEntries(t, func(heading string, e *ast.Entry) error {
+		if e == nil || used[e] {
+			return nil
+		}
+		headings[e] = heading
+		switch {
+		case sameWords(entryLines(e), c.After):
+			exact = append(exact, e)
+		case plainWords(e) == plainWords(after):
+			similar = append(similar, e)
+		}
+		return nil
+	})
+	// Prefer the entries still where the run put them.
+	inPlaceFirst := func(entries []*ast.Entry) []*ast.Entry {
+		var there, elsewhere []*ast.Entry
+		for _, e := range entries {
+			if headings[e] == c.To {
+				there = append(there, e)
+			} else {
+				elsewhere = append(elsewhere, e)
+			}
+		}
+		return append(there, elsewhere...)
+	}
+	exact, similar = inPlaceFirst(exact), inPlaceFirst(similar)
+
+	var e *ast.Entry
+	switch {
+	case len(exact) > 0:
+		e = exact[0]
+		*e = *before
+	case len(similar) == 1 || len(similar) > 1 && headings[similar[0]] == c.To && headings[similar[1]] != c.To:
+		e = similar[0]
+		revertEntry(e, before, after)
+	case len(similar) > 1:
+		return fmt.Errorf("more than one entry looks like it")
+	default:
+		return fmt.Errorf("no entry looks like it any more")
+	}
+	used[e] = true
+
+	if c.From != c.To && headings[e] == c.To {
+		removeEntry(t, e)
+		g := findGrouping(t, c.From)
+		g.Blocks = append(g.Blocks, ast.Block{Children: []*ast.Entry{e}})
+	}
+	return nil
+}
+
+// revertEntry undoes the change from before to after in e, for each date,
+// tag, and note that e still has as after left it.
+func revertEntry(e, before, after *ast.Entry) {
+	if e.Completed == after.Completed {
+		e.Completed = before.Completed
+	}
+	if sameDate(e.CompletionDate, after.CompletionDate) {
+		e.CompletionDate = before.CompletionDate
+	}
+	if sameDate(e.CreationDate, after.CreationDate) {
+		e.CreationDate = before.CreationDate
+	}
+	for _, key := range tagKeys(before, after) {
+		was, hadTag := before.Tag(key)
+		is, hasTag := after.Tag(key)
+		if was == is && hadTag == hasTag {
+			continue
+		}
+		if now, ok := e.Tag(key); now != is || ok != hasTag {
+			continue // Changed again since.
+		}
+		setTag(e, key, was, hadTag)
+	}
+	added := notesText(after)
+	for _, note := range notesText(before) {
+		if i := slices.Index(added, note); i >= 0 {
+			added = slices.Delete(added, i, i+1)
+		}
+	}
+	ast.SliceRemove(&e.Notes, func(note ast.NoteLine) bool {
+		i := slices.Index(added, strings.Join(note.Text, " "))
+		if i >= 0 {
+			added = slices.Delete(added, i, i+1)
+		}
+		return i >= 0
+	})
+}
+
+// describeChange says what a run did to an entry, going from before under
+// header from to after under header to.
+func describeChange(from, to string, before, after *ast.Entry) string {
+	var what []string
+	if from != to {
+		what = append(what, fmt.Sprintf("moved from %s to %s", from, to))
+	}
+	if !before.Completed && after.Completed {
+		what = append(what, "completed")
+	}
+	for _, date := range []struct {
+		name          string
+		before, after *dates.Date
+	}{
+		{"completion date", before.CompletionDate, after.CompletionDate},
+		{"creation date", before.CreationDate, after.CreationDate},
+	} {
+		switch {
+		case sameDate(date.before, date.after):
+		case date.before == nil:
+			what = append(what, fmt.Sprintf("added %s %s", date.name, date.after))
+		case date.after == nil:
+			what = append(what, fmt.Sprintf("removed %s %s", date.name, date.before))
+		default:
+			what = append(what, fmt.Sprintf("changed %s %s to %s", date.name, date.before, date.after))
+		}
+	}
+	for _, key := range tagKeys(before, after) {
+		was, hadTag := before.Tag(key)
+		is, hasTag := after.Tag(key)
+		switch {
+		case was == is && hadTag == hasTag:
+		case !hadTag:
+			what = append(what, fmt.Sprintf("added %s:%s", key, is))
+		case !hasTag:
+			what = append(what, fmt.Sprintf("removed %s:%s", key, was))
+		default:
+			what = append(what, fmt.Sprintf("rewrote %s:%s to %s:%s", key, was, key, is))
+		}
+	}
+	if n := len(after.Notes) - len(before.Notes); n > 0 {
+		what = append(what, fmt.Sprintf("added %d notes", n))
+	}
+	return strings.Join(what, ", ")
+}
+
+// parseJournalEntry parses the lines of an entry kept in the journal, keeping
+// them to write back as they were.
+func parseJournalEntry(text string) (*ast.Entry, error) {
+	t, err := parseFile("", []byte(text))
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Groupings) != 1 || len(t.Groupings[0].Header) != 0 || t.Groupings[0].Len() != 1 {
+		return nil, fmt.Errorf("want exactly one entry, got %q", text)
+	}
+	for _, b := range t.Groupings[0].Blocks {
+		if len(b.Children) > 0 {
+			return b.Children[0], nil
+		}
+	}
+	panic("unreachable")
+}
+
+// plainWords is an entry's description without its projects, contexts, and
+// tags, which are what people add to an entry after writing it.
+func plainWords(e *ast.Entry) string {
+	var words []string
+	for _, dp := range e.Description {
+		words = append(words, dp.Text...)
+	}
+	return strings.Join(words, " ")
+}
+
+// entryLines returns the lines the entry is written as, notes and all.
+func entryLines(e *ast.Entry) string {
+	var b strings.Builder
+	e.DumpText(&b)
+	return b.String()
+}
+
+// sameWords reports whether two texts differ only in spacing.
+func sameWords(a, b string) bool {
+	return slices.Equal(strings.Fields(a), strings.Fields(b))
+}
+
+func sameDate(a, b *dates.Date) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
+
+// tagKeys returns the keys of the tags of either entry, in order.
+func tagKeys(entries ...*ast.Entry) []string {
+	var keys []string
+	for _, e := range entries {
+		for _, dp := range e.Description {
+			if dp.SpecialTag != nil && !slices.Contains(keys, dp.SpecialTag.Key) {
+				keys = append(keys, dp.SpecialTag.Key)
+			}
+		}
+	}
+	return keys
+}
+
+// setTag sets the first tag with key to value, adding it if there is none, or
+// removes every tag with key if present is false.
+func setTag(e *ast.Entry, key, value string, present bool) {
+	if !present {
+		e.RemoveTag(key)
+		return
+	}
+	for _, dp := range e.Description {
+		if dp.SpecialTag != nil && dp.SpecialTag.Key == key {
+			dp.SpecialTag.Value = value
+			return
+		}
+	}
+	e.Description = append(e.Description, &ast.DescriptionPart{
+		SpecialTag: &ast.SpecialTag{Key: key, Value: value},
+	})
+}
+
+func notesText(e *ast.Entry) []string {
+	var notes []string
+	for _, note := range e.Notes {
+		notes = append(notes, strings.Join(note.Text, " "))
+	}
+	return notes
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+func TestUndo(t *testing.T) {
+	defer func(f string) { *filename = f }(*filename)
+	*filename = filepath.Join(t.TempDir(), "todo.txt")
+	write := func(contents string) {
+		if err := os.WriteFile(*filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func() string {
+		got, err := os.ReadFile(*filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(got)
+	}
+	format := func(now time.Time) {
+		var out bytes.Buffer
+		if err := Fmt(now, &out, []byte(read())); err != nil {
+			t.Fatal(err)
+		}
+		write(out.String())
+	}
+
+	original := `# Inbox
+
+  call   mom s:fri
+  fix bike due:tomorrow
+
+# Next
+
+  2022-01-01 plan trip
+`
+	write(original)
+	monday := time.Date(2024, time.May, 6, 9, 0, 0, 0, time.UTC)
+	// Printing the formatted file does not change it, and keeps no journal.
+	if err := Fmt(monday, &bytes.Buffer{}, []byte(original)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(*filename + journalSuffix); !os.IsNotExist(err) {
+		t.Errorf("formatting without -journal kept a journal: %v", err)
+	}
+	defer func(f string) { *journalFile = f }(*journalFile)
+	*journalFile = *filename + journalSuffix
+	format(monday)
+	format(monday.AddDate(0, 0, 4))
+	if want := `# Today
+
+  2024-05-06 fix bike due:2024-05-07
+  2024-05-06 call mom
+
+# Next
+
+  2022-01-01 plan trip
+`; read() != want {
+		t.Fatalf("unexpected formatted file (-got,+want):\n%s", cmp.Diff(read(), want))
+	}
+	journal, err := readJournal(*filename + journalSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var what []string
+	for _, run := range journal {
+		for _, c := range run.Changes {
+			what = append(what, c.What)
+		}
+	}
+	if diff := cmp.Diff(what, []string{
+		"added creation date 2024-05-06, rewrote due:tomorrow to due:2024-05-07",
+		"moved from Inbox to Scheduled, added creation date 2024-05-06, rewrote s:fri to s:2024-05-10",
+		"moved from Inbox to Today",
+		"moved from Scheduled to Today, removed s:2024-05-10",
+	}); diff != "" {
+		t.Errorf("unexpected journal (-got,+want):\n%s", diff)
+	}
+
+	// Edits since are kept.
+	write(strings.Replace(read(), "fix bike", "fix bike @garage", 1))
+	if err := runUndo([]string{"-f", *filename, "2"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Inbox
+
+  fix bike @garage due:tomorrow
+
+  call   mom s:fri
+
+# Next
+
+  2022-01-01 plan trip
+`
+	if diff := cmp.Diff(read(), want); diff != "" {
+		t.Errorf("unexpected file after undo (-got,+want):\n%s", diff)
+	}
+	if err := runUndo([]string{"-f", *filename}); err == nil {
+		t.Errorf("undo with an empty journal succeeded")
+	}
+}
+
+func TestUndoChange(t *testing.T) {
+	c := journalChange{
+		From:   "Inbox",
+		To:     "Scheduled",
+		Before: "  call mom s:fri\n",
+		After:  "  2024-05-06 call mom s:2024-05-10\n",
+	}
+	table := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{{
+		name:  "as left",
+		input: "# Scheduled\n\n  2024-05-06 call mom s:2024-05-10\n",
+		want:  "# Inbox\n\n  call mom s:fri\n",
+	}, {
+		name:  "rescheduled since",
+		input: "# Scheduled\n\n  2024-05-06 call mom +family s:2024-05-17\n",
+		want:  "# Inbox\n\n  call mom +family s:2024-05-17\n",
+	}, {
+		name:  "moved since",
+		input: "# Next\n\n  2024-05-06 call mom s:2024-05-10\n",
+		want:  "# Next\n\n  call mom s:fri\n",
+	}, {
+		name:    "ambiguous",
+		input:   "# Next\n\n  2024-05-06 call mom @phone\n  2024-05-06 call mom @home\n",
+		wantErr: true,
+	}, {
+		name:    "gone",
+		input:   "# Next\n\n  2024-05-06 call dad s:2024-05-10\n",
+		wantErr: true,
+	}}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			tree, err := parseFile("", []byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = undoChange(&tree, c, make(map[*ast.Entry]bool))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("undoChange succeeded, want an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			var got strings.Builder
+			if err := tree.DumpText(&got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got.String(), tc.want); diff != "" {
+				t.Errorf("unexpected file (-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// logPeriod is a span of time the Logbook is grouped by.
+type logPeriod struct {
+	// Name is the period in headings, like "Week".
+	Name string
+	// Key numbers the period a day is in, in time order.
+	Key func(d dates.Date) int
+	// Format writes a key in headings.
+	Format string
+}
+
+var logPeriods = map[string]logPeriod{
+	"week": {"Week", func(d dates.Date) int {
+		year, week := d.ISOWeek()
+		return year*100 + week
+	}, "%04d-W%02d"},
+	"month": {"Month", func(d dates.Date) int {
+		t := d.Time(time.UTC)
+		return t.Year()*100 + int(t.Month())
+	}, "%04d-%02d"},
+	"quarter": {"Quarter", func(d dates.Date) int {
+		t := d.Time(time.UTC)
+		return t.Year()*100 + (int(t.Month())+2)/3
+	}, "%04d-Q%d"},
+}
+
+// of returns the key of the period e was completed in, or 0 if it has no
+// valid completion date.
+func (p logPeriod) of(e *ast.Entry) int {
+	if e.CompletionDate == nil || !e.CompletionDate.Valid() {
+		return 0
+	}
+	return p.Key(*e.CompletionDate)
+}
+
+func (p logPeriod) heading(key, count int) string {
+	if key == 0 {
+		return fmt.Sprintf("## No date (%d)", count)
+	}
+	return fmt.Sprintf("## %s "+p.Format+" (%d)", p.Name, key/100, key%100, count)
+}
+
+// periodHeading matches the headings blockByPeriod writes, for any period.
+var periodHeading = regexp.MustCompile(`^## (Week \d{4}-W\d{2}|Month \d{4}-\d{2}|Quarter \d{4}-Q\d|No date) \(\d+\)$`)
+
+// blockByPeriod groups the Logbook into one block per period, newest first.
+// Newly completed entries land in the first block, and every other block
+// already holds a single period once the Logbook has been formatted, so those
+// blocks are kept as they are and only the entries of mixed blocks are moved.
+// Each block is still checked for its period, so the work is linear in the
+// size of the Logbook rather than in the number of new entries. With
+// headings, each block gets a heading with its period and count.
+func blockByPeriod(period logPeriod, headings bool) func([]ast.Block) []ast.Block {
+	return func(blocks []ast.Block) []ast.Block {
+		type keyedBlock struct {
+			key   int
+			block ast.Block
+		}
+		var (
+			grouped []keyedBlock
+			index   = make(map[int]int) // Period key to index in grouped.
+			text    []ast.Block         // Blocks without entries.
+		)
+		add := func(key int, entries []*ast.Entry, leading []string) {
+			if i, ok := index[key]; ok {
+				b := &grouped[i].block
+				b.Children = append(b.Children, entries...)
+				b.Leading = appendLeading(b.Leading, leading)
+				return
+			}
+			index[key] = len(grouped)
+			grouped = append(grouped, keyedBlock{key, ast.Block{
+				Children: slices.Clip(entries),
+				Leading:  leading,
+			}})
+		}
+
+		for i, b := range blocks {
+			var top []string
+			if i > 0 {
+				top = b.Leading // The first block's lines stay on top.
+			}
+			key, single := blockKey(period, b)
+			switch {
+			case !hasEntries(b):
+				text = append(text, b)
+			case single:
+				add(key, b.Children, top)
+			default:
+				// The lines above a mixed block go with its first entry.
+				for _, e := range b.Children {
+					if e != nil {
+						add(period.of(e), []*ast.Entry{e}, top)
+						top = nil
+					}
+				}
+			}
+		}
+
+		sort.SliceStable(grouped, func(i, j int) bool { return grouped[i].key > grouped[j].key })
+		result := make([]ast.Block, 0, len(grouped)+len(text))
+		for i, g := range grouped {
+			if i == 0 && len(blocks) > 0 {
+				// Whatever was on top stays there, before what this block had.
+				own := g.block.Leading
+				g.block.Leading = slices.Clone(blocks[0].Leading)
+				if !slices.Equal(own, blocks[0].Leading) {
+					g.block.Leading = appendLeading(g.block.Leading, own)
+				}
+			}
+			g.block.Leading = withoutPeriodHeadings(g.block.Leading)
+			if headings {
+				// Headings are set apart from what comes before them.
+				if n := len(g.block.Leading); n == 0 || strings.TrimSpace(g.block.Leading[n-1]) != "" {
+					g.block.Leading = append(g.block.Leading, "")
+				}
+				count := 0
+				for _, e := range g.block.Children {
+					if e != nil {
+						count++
+					}
+				}
+				g.block.Leading = append(g.block.Leading, period.heading(g.key, count))
+			}
+			result = append(result, g.block)
+		}
+		return append(result, text...)
+	}
+}
+
+// blockKey returns the period of the entries in b and whether they are all in
+// it.
+func blockKey(period logPeriod, b ast.Block) (key int, single bool) {
+	seen := false
+	for _, e := range b.Children {
+		if e == nil {
+			continue
+		}
+		k := period.of(e)
+		if seen && k != key {
+			return 0, false
+		}
+		key, seen = k, true
+	}
+	return key, true
+}
+
+// appendLeading adds the lines above a block to those above another block
+// that it is merged into. Old headings are dropped, and blank lines are not
+// doubled where the two meet.
+func appendLeading(lines, more []string) []string {
+	more = withoutPeriodHeadings(more)
+	if !slices.ContainsFunc(more, func(line string) bool { return strings.TrimSpace(line) != "" }) {
+		return lines
+	}
+	lines = withoutPeriodHeadings(lines)
+	if n := len(lines); n > 0 && strings.TrimSpace(lines[n-1]) == "" {
+		for len(more) > 0 && strings.TrimSpace(more[0]) == "" {
+			more = more[1:]
+		}
+	}
+	return append(slices.Clip(lines), more...)
+}
+
+func hasEntries(b ast.Block) bool {
+	return slices.ContainsFunc(b.Children, func(e *ast.Entry) bool { return e != nil })
+}
+
+// withoutPeriodHeadings drops the headings blockByPeriod wrote before, which
+// are written again with the counts as they are now.
+func withoutPeriodHeadings(lines []string) []string {
+	var result []string
+	for _, line := range lines {
+		if !periodHeading.MatchString(strings.TrimSpace(line)) {
+			result = append(result, line)
+		}
+	}
+	return result
+}
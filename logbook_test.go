@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+func TestLogbookGrouping(t *testing.T) {
+	defer func(by string, headings bool) {
+		*logBy, *logHeadings = by, headings
+	}(*logBy, *logHeadings)
+
+	now := time.Date(2024, time.May, 10, 0, 0, 0, 0, time.UTC)
+	input := `# Today
+
+x 2024-05-03 fix the sink
+
+# Logged
+
+x 2024-05-09 2024-05-01 call mom
+x 2024-03-29 2024-03-01 file taxes
+x 2024-05-06 2024-05-01 buy milk
+x 2024-04-02 2024-03-01 plant tomatoes
+
+x 2024-05-07 2024-05-01 water plants
+
+> Before this, see last year's file.
+`
+	table := []struct {
+		by       string
+		headings bool
+		want     string
+	}{{
+		by: "week",
+		want: `# Logged
+
+x 2024-05-10 2024-05-03 fix the sink
+x 2024-05-09 2024-05-01 call mom
+x 2024-05-07 2024-05-01 water plants
+x 2024-05-06 2024-05-01 buy milk
+
+x 2024-04-02 2024-03-01 plant tomatoes
+
+x 2024-03-29 2024-03-01 file taxes
+
+> Before this, see last year's file.
+`,
+	}, {
+		by:       "month",
+		headings: true,
+		want: `# Logged
+
+## Month 2024-05 (4)
+x 2024-05-10 2024-05-03 fix the sink
+x 2024-05-09 2024-05-01 call mom
+x 2024-05-07 2024-05-01 water plants
+x 2024-05-06 2024-05-01 buy milk
+
+## Month 2024-04 (1)
+x 2024-04-02 2024-03-01 plant tomatoes
+
+## Month 2024-03 (1)
+x 2024-03-29 2024-03-01 file taxes
+
+> Before this, see last year's file.
+`,
+	}, {
+		by:       "quarter",
+		headings: true,
+		want: `# Logged
+
+## Quarter 2024-Q2 (5)
+x 2024-05-10 2024-05-03 fix the sink
+x 2024-05-09 2024-05-01 call mom
+x 2024-05-07 2024-05-01 water plants
+x 2024-05-06 2024-05-01 buy milk
+x 2024-04-02 2024-03-01 plant tomatoes
+
+## Quarter 2024-Q1 (1)
+x 2024-03-29 2024-03-01 file taxes
+
+> Before this, see last year's file.
+`,
+	}}
+	for _, tc := range table {
+		t.Run(tc.by, func(t *testing.T) {
+			*logBy, *logHeadings = tc.by, tc.headings
+			var got bytes.Buffer
+			if err := Fmt(now, &got, []byte(input)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got.String(), tc.want); diff != "" {
+				t.Errorf("unexpected Logbook (-got,+want):\n%s", diff)
+			}
+			if err := verifyFormat(now, []byte(input), got.Bytes()); err != nil {
+				t.Errorf("verifyFormat: %v", err)
+			}
+		})
+	}
+}
+
+func TestLogbookHeadingsUpdate(t *testing.T) {
+	defer func(by string, headings bool) {
+		*logBy, *logHeadings = by, headings
+	}(*logBy, *logHeadings)
+	*logBy, *logHeadings = "week", true
+
+	now := time.Date(2024, time.May, 10, 0, 0, 0, 0, time.UTC)
+	input := `# Today
+
+x 2024-05-03 fix the sink
+
+# Logged
+
+## Week 2024-W19 (1)
+x 2024-05-09 2024-05-01 call mom
+
+## Week 2024-W13 (1)
+x 2024-03-29 2024-03-01 file taxes
+`
+	want := `# Logged
+
+## Week 2024-W19 (2)
+x 2024-05-10 2024-05-03 fix the sink
+x 2024-05-09 2024-05-01 call mom
+
+## Week 2024-W13 (1)
+x 2024-03-29 2024-03-01 file taxes
+`
+	var got bytes.Buffer
+	if err := Fmt(now, &got, []byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("unexpected Logbook (-got,+want):\n%s", diff)
+	}
+
+	// Without headings, the old ones go away.
+	*logHeadings = false
+	got.Reset()
+	if err := Fmt(now, &got, []byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	want = `# Logged
+
+x 2024-05-10 2024-05-03 fix the sink
+x 2024-05-09 2024-05-01 call mom
+
+x 2024-03-29 2024-03-01 file taxes
+`
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("unexpected Logbook without headings (-got,+want):\n%s", diff)
+	}
+}
+
+func TestLogbookKeepsText(t *testing.T) {
+	defer func(by string, headings bool) {
+		*logBy, *logHeadings = by, headings
+	}(*logBy, *logHeadings)
+	*logBy, *logHeadings = "week", false
+
+	now := time.Date(2024, time.May, 10, 0, 0, 0, 0, time.UTC)
+	input := `# Logged
+
+x 2024-05-09 2024-05-01 call mom
+
+> Vacation notes: keep this
+
+x 2024-03-29 2024-03-01 file taxes
+x 2024-05-06 2024-05-01 buy milk
+
+> Also this
+
+x 2024-05-07 2024-05-01 water plants
+`
+	want := `# Logged
+
+> Also this
+
+x 2024-05-09 2024-05-01 call mom
+x 2024-05-07 2024-05-01 water plants
+x 2024-05-06 2024-05-01 buy milk
+
+> Vacation notes: keep this
+
+x 2024-03-29 2024-03-01 file taxes
+`
+	var got bytes.Buffer
+	if err := Fmt(now, &got, []byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("unexpected Logbook (-got,+want):\n%s", diff)
+	}
+	if err := verifyFormat(now, []byte(input), got.Bytes()); err != nil {
+		t.Errorf("verifyFormat: %v", err)
+	}
+}
+
+// BenchmarkBlockByPeriod regroups a Logbook of years of weeks after a few
+// entries were completed. Only those entries move, but every block is still
+// checked for its period.
+func BenchmarkBlockByPeriod(b *testing.B) {
+	var blocks []ast.Block
+	day := dates.NewDate(2024, time.May, 6)
+	blocks = append(blocks, ast.Block{})
+	for i := 0; i < 7; i++ {
+		blocks[0].Children = append(blocks[0].Children, completedOn(day.AddDays(-i*200)))
+	}
+	for week := 1; week < 500; week++ {
+		var block ast.Block
+		for i := 0; i < 20; i++ {
+			block.Children = append(block.Children, completedOn(day.AddDays(-7*week)))
+		}
+		blocks = append(blocks, block)
+	}
+	reblock := blockByPeriod(logPeriods["week"], true)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reblock(slices.Clone(blocks))
+	}
+}
+
+func completedOn(d dates.Date) *ast.Entry {
+	return &ast.Entry{Completed: true, CompletionDate: &d}
+}
@@ -1,35 +1,51 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"slices"
 	"strings"
 	"time"
 
 	"github.com/spencer-p/vogon/pkg/ast"
 	"github.com/spencer-p/vogon/pkg/dates"
 	"github.com/spencer-p/vogon/pkg/parse"
-
-	"github.com/alecthomas/participle/v2"
 )
 
 const (
-	dateFmt = "2006-01-02"
+	dateFmt     = "2006-01-02"
+	dateTimeFmt = "2006-01-02T15:04"
 )
 
 var (
 	ebnf     = flag.Bool("ebnf", false, "Output EBNF")
 	verbose  = flag.Bool("v", false, "Print more")
+	verify   = flag.Bool("verify", false, "Fail rather than format if formatting again would change the result, or if any entry would be lost or duplicated")
 	filename = flag.String("f", "-", "todo.txt file path to process")
+	clock    = newClockFlags(flag.CommandLine)
+
+	staleDays    = flag.Int("stale", 0, "Tag Next and Inbox entries at least this many days old with their age, or 0 to disable")
+	demoteDays   = flag.Int("demote", 0, "Move Next and Inbox entries at least this many days old to Someday, or 0 to disable")
+	rollHolidays = flag.Bool("roll-holidays", false, "Move tasks scheduled on a holiday to the next working day")
+	eveningHour  = flag.Int("evening", 0, "Move today's entries at or after this hour to Evening, or 0 to disable")
+	logBy        = flag.String("log-by", "week", "Group the Logbook by week, month, or quarter")
+	logHeadings  = flag.Bool("log-headings", false, "Write a ## heading with a count above each group in the Logbook")
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	flag.Parse()
 
 	rawInputCh := make(chan []byte)
@@ -56,9 +72,8 @@ func main() {
 		rawInputCh <- buf.Bytes()
 	}()
 
-	parser := parse.BuildParser()
 	if *ebnf {
-		fmt.Println(parser.String())
+		fmt.Println(parse.BuildParser().String())
 		return
 	}
 
@@ -66,7 +81,13 @@ func main() {
 	if !ok {
 		return
 	}
-	err := Fmt(parser, time.Now(), os.Stdout, rawInput)
+	now, err := clock.Now()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Stderr.Write(rawInput)
+		os.Exit(1)
+	}
+	err = Fmt(now, os.Stdout, rawInput)
 	if err != nil {
 		// If formatting failed, dump the original + an error.
 		fmt.Fprintln(os.Stderr, err)
@@ -76,9 +97,12 @@ func main() {
 
 }
 
-func Fmt(parser *participle.Parser, now time.Time, output io.Writer, input []byte) error {
-	var t ast.TodoTxt
-	if err := parser.ParseBytes("", input, &t); err != nil {
+func Fmt(now time.Time, output io.Writer, input []byte) error {
+	if _, ok := logPeriods[*logBy]; !ok {
+		return fmt.Errorf("unknown -log-by %q, want week, month, or quarter", *logBy)
+	}
+	t, err := parse.Parse("", input)
+	if err != nil {
 		return fmt.Errorf("parse error: %w", err)
 	}
 
@@ -91,16 +115,125 @@ func Fmt(parser *participle.Parser, now time.Time, output io.Writer, input []byt
 		}
 	}
 
-	today := now.Format(dateFmt)
+	// Whoever writes the output back to the file names the journal.
+	journal := *journalFile
+	var snapshot map[*ast.Entry]entrySnapshot
+	if journal != "" {
+		snapshot = snapshotEntries(&t)
+	}
+	formatted, err := formatTree(&t, now)
+	if err != nil {
+		return err
+	}
+	if *verify {
+		if err := verifyFormat(now, input, formatted); err != nil {
+			return fmt.Errorf("refusing to format: %w", err)
+		}
+	}
+	if err := appendJournal(journal, now, journalChanges(snapshot, &t)); err != nil {
+		return fmt.Errorf("unable to write journal: %w", err)
+	}
+	_, err = output.Write(formatted)
+	return err
+}
+
+// formatTree formats a parsed file, leaving t as it is written.
+func formatTree(t *ast.TodoTxt, now time.Time) ([]byte, error) {
+	// Other files are left alone, but the includes are kept.
+	includes := cutIncludes(t)
+	*t = compileTodoTxt(*t, now)
+
+	var buf bytes.Buffer
+	if err := dumpWithIncludes(&buf, includes, *t); err != nil {
+		return nil, fmt.Errorf("unable to format: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// maxCompilePasses bounds how many times compileTodoTxt runs the header rules
+// looking for a fixed point. Files settle after one pass, unless a rule starts
+// to depend on what another did.
+const maxCompilePasses = 10
+
+// compileTodoTxt runs the formatter's header rules over a parsed file until
+// they no longer change it, so that formatting the result again leaves it as
+// it is.
+func compileTodoTxt(t ast.TodoTxt, now time.Time) ast.TodoTxt {
+	var last string
+	for pass := 0; pass < maxCompilePasses; pass++ {
+		t = compilePass(t, now)
+		var b strings.Builder
+		t.DumpText(&b)
+		if b.String() == last {
+			break
+		}
+		last = b.String()
+	}
+	return t
+}
+
+// compilePass runs the formatter's header rules over a file once.
+func compilePass(t ast.TodoTxt, now time.Time) ast.TodoTxt {
+	today := dates.DateOf(now)
 	visitAllEntries(&t, func(heading string, entry *ast.Entry) error {
 		// Add creation dates.
 		if entry.CreationDate == nil {
 			entry.CreationDate = &today
 		}
+		ageEntry(now, heading, entry)
 		return nil
 	})
 
-	t = Compile(t, []HeaderCompiler{{
+	isToday := func(e *ast.Entry) bool {
+		if hasSnooze(now, e) {
+			return false // Scheduled first.
+		}
+		dueDate, hasDueDate := e.DueDate()
+		scheduledFor, hasScheduled := e.ScheduledFor()
+		if !hasDueDate && !hasScheduled {
+			return false // No scheduled or due date.
+		}
+
+		// Check for scheduled date.
+		// Accept "t", "today", and the formatted date for today.
+		scheduledDay, _, _ := dates.CutTime(scheduledFor)
+		norm, err := dates.ParseDay(now, scheduledFor)
+		if scheduledDay == "t" || scheduledDay == "today" || (err == nil && !norm.After(today)) {
+			return true
+		}
+
+		// Check for due date.
+		norm, err = dates.ParseDay(now, dueDate)
+		if err == nil && !norm.After(today) {
+			return true
+		}
+		return false
+	}
+	isEvening := func(e *ast.Entry) bool {
+		if *eveningHour <= 0 {
+			return false
+		}
+		clock, ok := entryClock(e)
+		return ok && clock >= time.Duration(*eveningHour)*time.Hour
+	}
+
+	// Evening is a manual header, but today's entries late enough in the day
+	// go there too.
+	evening := manualHeader("Evening", now)
+	manualEvening := evening.Filter
+	evening.Filter = func(header string, e *ast.Entry) bool {
+		return manualEvening(header, e) || ((header == "Today" || isToday(e)) && isEvening(e))
+	}
+
+	// Waiting is a manual header too, but it also takes the entries that are
+	// waiting on someone.
+	waiting := manualHeader("Waiting", now)
+	manualWaiting := waiting.Filter
+	waiting.Filter = func(header string, e *ast.Entry) bool {
+		return manualWaiting(header, e) || isDelegated(now, header, e)
+	}
+
+	return Compile(t, []HeaderCompiler{{
 		Header: "Logged",
 		Filter: func(header string, e *ast.Entry) bool { return e.Completed == true },
 		Transform: func(e *ast.Entry) *ast.Entry {
@@ -110,61 +243,55 @@ func Fmt(parser *participle.Parser, now time.Time, output io.Writer, input []byt
 			}
 			return e
 		},
-		SortLess: func(l, r *ast.Entry) bool { return *l.CompletionDate > *r.CompletionDate },
-		ReBlock:  blockByWeek,
+		SortLess: func(l, r *ast.Entry) bool { return l.CompletionDate.After(*r.CompletionDate) },
+		ReBlock:  blockByPeriod(logPeriods[*logBy], *logHeadings),
 	}, {
 		Header: "Today",
 		Filter: func(header string, e *ast.Entry) bool {
-			dueDate, hasDueDate := e.DueDate()
-			scheduledFor, hasScheduled := e.ScheduledFor()
-			if !hasDueDate && !hasScheduled {
-				return false // No scheduled or due date.
-			}
-
-			// Check for scheduled date.
-			// Accept "t", "today", and the formatted date for today.
-			norm, err := normalizeDate(now, scheduledFor)
-			if scheduledFor == "t" || scheduledFor == "today" || (err == nil && norm <= today) {
-				return true
-			}
-
-			// Check for due date.
-			norm, err = normalizeDate(now, dueDate)
-			if err == nil && norm <= today {
-				return true
-			}
-			return false
-
+			return (isToday(e) && !isEvening(e)) || isFollowUp(now, e)
 		},
 		Transform: func(e *ast.Entry) *ast.Entry {
+			followUp(now, e)
+			keepScheduledTime(e)
 			ast.SliceRemove(&(*e).Description, func(dp *ast.DescriptionPart) bool {
 				return dp.SpecialTag != nil && ast.StringIsScheduled(dp.SpecialTag.Key)
 			})
 			return e
 		},
-		// No sorting for Today.
+		// Entries with a time of day come first, in order.
+		SortLess: func(l, r *ast.Entry) bool {
+			clockLeft, okLeft := entryClock(l)
+			clockRight, okRight := entryClock(r)
+			if okLeft && okRight {
+				return clockLeft < clockRight
+			}
+			return okLeft && !okRight
+		},
 	}, manualHeader(
 		"Next", now,
 	), manualHeader(
 		"Someday", now,
-	), manualHeader(
-		"Waiting", now,
-	), manualHeader(
-		"Evening", now,
-	), {
+	), waiting, evening, {
 		Header: "Scheduled",
-		Filter: func(header string, e *ast.Entry) bool { _, ok := e.ScheduledFor(); return ok },
+		Filter: func(header string, e *ast.Entry) bool {
+			_, ok := e.ScheduledFor()
+			return ok || hasSnooze(now, e)
+		},
 		SortLess: func(l, r *ast.Entry) bool {
 			schedLeft, _ := l.ScheduledFor()
 			schedRight, _ := r.ScheduledFor()
-			return maybeNormalizeDate(now, schedLeft) < maybeNormalizeDate(now, schedRight)
+			return compareDateValues(now, schedLeft, schedRight) < 0
 		},
 		Transform: func(e *ast.Entry) *ast.Entry {
+			snoozeEntry(now, e)
 			// Rewrite the scheduled date to canonical form instead of relative
 			// form, if needed.
 			for i := range e.Description {
 				if e.Description[i].SpecialTag != nil && ast.StringIsScheduled(e.Description[i].SpecialTag.Key) {
 					date := maybeNormalizeDate(now, e.Description[i].SpecialTag.Value)
+					if *rollHolidays {
+						date = rollOffHoliday(date)
+					}
 					e.Description[i].SpecialTag.Value = date
 				}
 			}
@@ -174,7 +301,7 @@ func Fmt(parser *participle.Parser, now time.Time, output io.Writer, input []byt
 		Header: "Inbox",
 		Filter: func(header string, e *ast.Entry) bool { return header == "" },
 		Transform: func(e *ast.Entry) *ast.Entry {
-			normalizeDateTag(e, now, "due")
+			normalizeDateTag(e, now, "due", followTag)
 			return e
 		},
 	}, {
@@ -183,13 +310,6 @@ func Fmt(parser *participle.Parser, now time.Time, output io.Writer, input []byt
 		Transform: func(e *ast.Entry) *ast.Entry { return e },
 		SortLess:  func(l, r *ast.Entry) bool { return false },
 	}})
-
-	bufOutput := bufio.NewWriter(output)
-	err := t.DumpText(bufOutput)
-	if err != nil {
-		return fmt.Errorf("unable to format: %w", err)
-	}
-	return bufOutput.Flush()
 }
 
 func visitAllEntries(t *ast.TodoTxt, visit func(heading string, e *ast.Entry) error) error {
@@ -238,21 +358,48 @@ func findGrouping(t *ast.TodoTxt, name string) *ast.Grouping {
 	return &t.Groupings[len(t.Groupings)-1]
 }
 
+// maybeNormalizeDate rewrites a relative date to YYYY-MM-DD, or
+// YYYY-MM-DDTHH:MM if it has a time of day. Dates it does not understand are
+// returned as is.
 func maybeNormalizeDate(now time.Time, date string) string {
-	if norm, err := dates.ParseRelative(now, date); err == nil {
+	if norm, hasClock, err := dates.ParseRelativeTime(now, date); err == nil {
+		if hasClock {
+			return norm.Format(dateTimeFmt)
+		}
 		return norm.Format(dateFmt)
 	}
 	return date
 }
 
-func normalizeDate(now time.Time, date string) (string, error) {
-	if norm, err := dates.ParseRelative(now, date); err == nil {
-		return norm.Format(dateFmt), nil
+// rollOffHoliday moves a YYYY-MM-DD date, which may have a time of day, to
+// the next working day if it falls on a holiday.
+func rollOffHoliday(date string) string {
+	day, clock, hasClock := dates.CutTime(date)
+	d, err := dates.ParseDate(day)
+	if err != nil || !dates.ActiveCalendar.IsHoliday(d) {
+		return date
 	}
-	if _, err := time.Parse(dateFmt, date); err == nil {
-		return date, nil
+	rolled := dates.ActiveCalendar.RollForward(d).String()
+	if hasClock {
+		return rolled + "T" + clock
+	}
+	return rolled
+}
+
+// compareDateValues orders two date-valued tags by when they happen. Values
+// that are not dates come last, in the order of their text.
+func compareDateValues(now time.Time, l, r string) int {
+	timeLeft, _, errLeft := dates.ParseRelativeTime(now, l)
+	timeRight, _, errRight := dates.ParseRelativeTime(now, r)
+	switch {
+	case errLeft == nil && errRight == nil:
+		return timeLeft.Compare(timeRight)
+	case errLeft == nil:
+		return -1
+	case errRight == nil:
+		return 1
 	}
-	return "", fmt.Errorf("date %q is not a relative date or YYYY-MM-DD", date)
+	return strings.Compare(l, r)
 }
 
 func manualHeader(headerName string, now time.Time) HeaderCompiler {
@@ -266,10 +413,11 @@ func manualHeader(headerName string, now time.Time) HeaderCompiler {
 			return ok && move == strings.ToLower(headerName)
 		},
 		Transform: func(e *ast.Entry) *ast.Entry {
+			keepScheduledTime(e)
 			e.RemoveTag("move")
 			e.RemoveTag("sched")
 			e.RemoveTag("s")
-			normalizeDateTag(e, now, "due")
+			normalizeDateTag(e, now, "due", followTag)
 			return e
 		},
 	}
@@ -288,47 +436,3 @@ func normalizeDateTag(e *ast.Entry, now time.Time, tags ...string) {
 		}
 	}
 }
-
-func partition[T any](l []T, f func(T) bool) [][]T {
-	head := make([]T, 0)
-	tail := make([]T, 0)
-	for _, li := range l {
-		if f(li) {
-			head = append(head, li)
-		} else {
-			tail = append(tail, li)
-		}
-	}
-	return [][]T{head, tail}
-}
-
-// blockByWeek splits the first block based on the week it was completed in.
-// It only splits the first week to avoid sorting an entire logbook.
-// The first block is only split in two. The first new block may have
-// completion dates spread over multiple weeks. The second new block is
-// guaranteed to be only items completed in a single week (the oldest week
-// present in the initial first block).
-// Repeating this process converges on fully split weeks.
-func blockByWeek(blocks []ast.Block) []ast.Block {
-	if len(blocks) == 0 {
-		return blocks
-	}
-	if len(blocks[0].Children) == 0 {
-		return blocks
-	}
-
-	firstblock := blocks[0]
-	minweek := firstblock.Children[0].CompletedWeek()
-	for _, e := range firstblock.Children {
-		if week := e.CompletedWeek(); week < minweek {
-			minweek = week
-		}
-	}
-	split := partition(firstblock.Children, func(e *ast.Entry) bool {
-		return e.CompletedWeek() > minweek
-	})
-	return slices.Replace(blocks, 0, 1,
-		ast.Block{Children: split[0]},
-		ast.Block{Children: split[1]},
-	)
-}
@@ -3,7 +3,6 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"io"
 	"io/fs"
 	"io/ioutil"
 	"path/filepath"
@@ -12,7 +11,6 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
-	"github.com/spencer-p/vogon/pkg/parse"
 )
 
 const (
@@ -64,11 +62,10 @@ func TestFmt(t *testing.T) {
 	}
 
 	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
-	parser := parse.BuildParser()
 	for _, tc := range table {
 		t.Run(tc.name, func(t *testing.T) {
 			got := new(bytes.Buffer)
-			err := Fmt(parser, now, got, tc.input)
+			err := Fmt(now, got, tc.input)
 			if err != nil {
 				t.Errorf("unexpected error from Fmt: %v", err)
 				return
@@ -76,6 +73,9 @@ func TestFmt(t *testing.T) {
 			if diff := cmp.Diff(got.String(), string(tc.output)); diff != "" {
 				t.Errorf("Fmt() returned unexpected result (-got,+want):\n%s", diff)
 			}
+			if err := verifyFormat(now, tc.input, got.Bytes()); err != nil {
+				t.Errorf("verifyFormat: %v", err)
+			}
 		})
 	}
 }
@@ -99,13 +99,18 @@ func FuzzFmt(f *testing.F) {
 	}
 
 	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
-	parser := parse.BuildParser()
 	f.Fuzz(func(t *testing.T, s string) {
 		defer func() {
 			if r := recover(); r != nil {
 				t.Errorf("panic: %v", r)
 			}
 		}()
-		Fmt(parser, now, io.Discard, []byte(s))
+		var got bytes.Buffer
+		if err := Fmt(now, &got, []byte(s)); err != nil {
+			return
+		}
+		if err := verifyFormat(now, []byte(s), got.Bytes()); err != nil {
+			t.Errorf("verifyFormat: %v", err)
+		}
 	})
 }
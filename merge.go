@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+// conflictTag marks both versions of an entry that was changed differently
+// on each side of a merge, as in conflict:ours and conflict:theirs.
+const conflictTag = "conflict"
+
+// mergeEntry is one side's version of an entry.
+type mergeEntry struct {
+	Header []string
+	Entry  *ast.Entry
+	text   string
+	block  int // The block under Header the entry is in.
+}
+
+// runMerge is a git merge driver. It merges the entries of theirs into ours,
+// leaving the formatted result in ours, and fails if any entries conflict.
+// Configure it with
+//
+//	git config merge.vogon.driver "vogon merge %O %A %B"
+//
+// and a line like "todo.txt merge=vogon" in .gitattributes.
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vogon merge [flags] base ours theirs")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		fs.Usage()
+		return fmt.Errorf("merge needs the base, ours, and theirs files")
+	}
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	var trees [3]ast.TodoTxt
+	var includes [3][]string
+	for i, name := range fs.Args() {
+		input, err := readInput(name)
+		if err != nil {
+			return err
+		}
+		if trees[i], err = parseFile(name, input); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		includes[i] = cutIncludes(&trees[i])
+	}
+
+	merged, conflicts := mergeTodoTxt(trees[0], trees[1], trees[2])
+	merged = compileTodoTxt(merged, now)
+	var buf bytes.Buffer
+	if err := dumpWithIncludes(&buf, mergeIncludes(includes[1], includes[2]), merged); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(fs.Arg(1), buf.Bytes()); err != nil {
+		return err
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d conflicting entries, tagged %s:ours and %s:theirs", conflicts, conflictTag, conflictTag)
+	}
+	return nil
+}
+
+// mergeTodoTxt merges ours and theirs entry by entry. Entries added on either
+// side are kept, and an entry changed on only one side takes that change. An
+// entry completed on either side is completed. Any other entry changed on
+// both sides is a conflict, and both versions are kept with a conflict tag.
+// The lines between entries, like comments and prose, are merged the same
+// way, a paragraph at a time.
+func mergeTodoTxt(base, ours, theirs ast.TodoTxt) (ast.TodoTxt, int) {
+	_, baseEntries := indexEntries(base)
+	oursKeys, oursEntries := indexEntries(ours)
+	theirsKeys, theirsEntries := indexEntries(theirs)
+
+	keys := oursKeys
+	for _, key := range theirsKeys {
+		if _, ok := oursEntries[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	// The result starts out as ours without its entries, so that the lines
+	// between them stay where they are.
+	baseText, theirsText := mergeTrivia(base), mergeTrivia(theirs)
+	var result ast.TodoTxt
+	index := make(map[string]int)
+	for _, g := range ours.Groupings {
+		name := strings.Join(g.Header, " ")
+		if _, ok := index[name]; ok {
+			continue
+		}
+		index[name] = len(result.Groupings)
+		skeleton := ast.Grouping{Header: g.Header, Source: g.Source}
+		for _, b := range g.Blocks {
+			leading := b.Leading
+			if text := strings.Join(leading, "\n"); baseText[text] && !theirsText[text] {
+				leading = nil // Removed by them.
+			}
+			skeleton.Blocks = append(skeleton.Blocks, ast.Block{Leading: leading})
+		}
+		result.Groupings = append(result.Groupings, skeleton)
+	}
+	grouping := func(header []string) *ast.Grouping {
+		name := strings.Join(header, " ")
+		i, ok := index[name]
+		if !ok {
+			i = len(result.Groupings)
+			index[name] = i
+			result.Groupings = append(result.Groupings, ast.Grouping{Header: header})
+		}
+		g := &result.Groupings[i]
+		if len(g.Blocks) == 0 {
+			g.Blocks = []ast.Block{{}}
+		}
+		return g
+	}
+	add := func(key string, m mergeEntry) {
+		g := grouping(m.Header)
+		// An entry that stays where it was in ours goes back to its block.
+		block := 0
+		if o, ok := oursEntries[key]; ok && sameHeader(o.Header, m.Header) && o.block < len(g.Blocks) {
+			block = o.block
+		}
+		g.Blocks[block].Children = append(g.Blocks[block].Children, m.Entry)
+	}
+
+	conflicts := 0
+	conflict := func(key string, o, t *mergeEntry) {
+		conflicts++
+		for side, m := range []*mergeEntry{o, t} {
+			if m == nil {
+				continue
+			}
+			m.Entry.Description = append(m.Entry.Description, &ast.DescriptionPart{
+				SpecialTag: &ast.SpecialTag{Key: conflictTag, Value: []string{"ours", "theirs"}[side]},
+			})
+			add(key, *m)
+		}
+	}
+
+	for _, key := range keys {
+		b, inBase := baseEntries[key]
+		o, inOurs := oursEntries[key]
+		t, inTheirs := theirsEntries[key]
+		switch {
+		case inOurs && inTheirs:
+			if m, ok := merge3(b, inBase, o, t); ok {
+				add(key, m)
+			} else {
+				conflict(key, &o, &t)
+			}
+		case inOurs && !inBase:
+			add(key, o) // Added by us.
+		case inOurs && o.text != b.text:
+			conflict(key, &o, nil) // Changed by us, removed by them.
+		case inTheirs && !inBase:
+			add(key, t) // Added by them.
+		case inTheirs && t.text != b.text:
+			conflict(key, nil, &t) // Removed by us, changed by them.
+		}
+	}
+
+	// Lines added by them go before the block they were above.
+	oursText := mergeTrivia(ours)
+	for _, g := range theirs.Groupings {
+		for bi, b := range g.Blocks {
+			text := strings.Join(b.Leading, "\n")
+			if !theirsText[text] || baseText[text] || oursText[text] {
+				continue
+			}
+			merged := grouping(g.Header)
+			if merged.Source == "" {
+				merged.Source = g.Source
+			}
+			at := bi
+			if at > len(merged.Blocks) {
+				at = len(merged.Blocks)
+			}
+			merged.Blocks = slices.Insert(merged.Blocks, at, ast.Block{Leading: b.Leading})
+		}
+	}
+	return result, conflicts
+}
+
+// mergeTrivia returns the runs of lines between the entries of t that have
+// more than blank lines, each joined into one string.
+func mergeTrivia(t ast.TodoTxt) map[string]bool {
+	result := make(map[string]bool)
+	for _, g := range t.Groupings {
+		for _, b := range g.Blocks {
+			if text := strings.Join(b.Leading, "\n"); strings.TrimSpace(text) != "" {
+				result[text] = true
+			}
+		}
+	}
+	return result
+}
+
+// merge3 merges two versions of the same entry. It fails if both sides made
+// different changes, unless one of them only completed the entry, in which
+// case the other side's changes are kept and the entry is completed.
+func merge3(b mergeEntry, inBase bool, o, t mergeEntry) (mergeEntry, bool) {
+	var m mergeEntry
+	switch {
+	case o.text == t.text:
+		m = o
+	case inBase && o.text == b.text:
+		m = t
+	case inBase && t.text == b.text:
+		m = o
+	case o.Entry.Completed != t.Entry.Completed:
+		done, open := o, t
+		if t.Entry.Completed {
+			done, open = t, o
+		}
+		reopened := *done.Entry
+		reopened.Completed, reopened.CompletionDate = false, nil
+		switch undone := mergeText(&reopened); {
+		case undone == open.text:
+			m = done
+		case inBase && undone == b.text:
+			completed := *open.Entry
+			completed.Completed, completed.CompletionDate = true, done.Entry.CompletionDate
+			m = mergeEntry{Entry: &completed, text: mergeText(&completed)}
+		default:
+			return mergeEntry{}, false
+		}
+	default:
+		return mergeEntry{}, false
+	}
+
+	// Moving an entry is merged separately from editing it. If both sides
+	// moved it somewhere different, ours wins.
+	switch {
+	case sameHeader(o.Header, t.Header):
+		m.Header = o.Header
+	case inBase && sameHeader(o.Header, b.Header):
+		m.Header = t.Header
+	default:
+		m.Header = o.Header
+	}
+	return m, true
+}
+
+// indexEntries returns the keys of the entries of t in order, and the entries
+// by key. Entries are known by their id tag, or else by the words of their
+// description, so that adding a project, context, or tag is an edit rather
+// than a new entry. Repeated keys are numbered so that each key is unique.
+func indexEntries(t ast.TodoTxt) ([]string, map[string]mergeEntry) {
+	var keys []string
+	entries := make(map[string]mergeEntry)
+	seen := make(map[string]int)
+	for _, g := range t.Groupings {
+		for bi, b := range g.Blocks {
+			for _, e := range b.Children {
+				key := entryKey(e)
+				seen[key]++
+				if n := seen[key]; n > 1 {
+					key = fmt.Sprintf("%s#%d", key, n)
+				}
+				keys = append(keys, key)
+				entries[key] = mergeEntry{Header: g.Header, Entry: e, text: mergeText(e), block: bi}
+			}
+		}
+	}
+	return keys, entries
+}
+
+// mergeText returns e the way it is written, but without its age tag and
+// spacing. Ages are recomputed when formatting, and would otherwise conflict
+// whenever both sides were formatted on different days.
+func mergeText(e *ast.Entry) string {
+	c := *e
+	c.Source = nil
+	c.Description = slices.DeleteFunc(slices.Clone(e.Description), func(dp *ast.DescriptionPart) bool {
+		return dp.SpecialTag != nil && dp.SpecialTag.Key == "age"
+	})
+	var buf bytes.Buffer
+	c.DumpText(&buf)
+	return buf.String()
+}
+
+func entryKey(e *ast.Entry) string {
+	if id, ok := e.Tag("id"); ok {
+		return "id:" + id
+	}
+	var words []string
+	for _, dp := range e.Description {
+		words = append(words, dp.Text...)
+	}
+	if len(words) == 0 {
+		return e.DescriptionText()
+	}
+	return strings.Join(words, " ")
+}
+
+func sameHeader(a, b []string) bool {
+	return strings.Join(a, " ") == strings.Join(b, " ")
+}
+
+// mergeIncludes keeps every include from either side, in order.
+func mergeIncludes(ours, theirs []string) []string {
+	result := slices.Clone(ours)
+	for _, include := range theirs {
+		if !slices.Contains(result, include) {
+			result = append(result, include)
+		}
+	}
+	return result
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+func TestMerge(t *testing.T) {
+	base := []string{
+		"# Inbox",
+		"",
+		"  2022-01-01 call mom",
+		"  2022-01-01 water the plants",
+		"  2022-01-01 fix the printer",
+		"  2022-01-01 renew passport",
+		"  2022-01-01 old idea",
+	}
+	ours := []string{
+		"# Inbox",
+		"",
+		"  2022-01-01 call mom",
+		"  2022-01-01 fix the printer due:2022-01-05",
+		"  2022-01-01 renew passport id:7 @errands",
+		"  2022-01-01 old idea +someday",
+		"  2022-01-01 buy milk",
+		"",
+		"# Next",
+		"",
+		"  2022-01-01 water the plants",
+	}
+	theirs := []string{
+		"# Inbox",
+		"",
+		"  2022-01-01 fix the printer due:2022-01-06",
+		"  2022-01-01 water the plants age:20d",
+		"  2022-01-01 renew passport id:7 +travel",
+		"  2022-01-01 buy stamps",
+		"",
+		"# Logged",
+		"",
+		"x 2022-01-02 2022-01-01 call mom",
+	}
+	want := strings.Join([]string{
+		"# Inbox",
+		"",
+		"  2022-01-01 fix the printer due:2022-01-05 conflict:ours",
+		"  2022-01-01 fix the printer due:2022-01-06 conflict:theirs",
+		"  2022-01-01 renew passport id:7 @errands conflict:ours",
+		"  2022-01-01 renew passport id:7 +travel conflict:theirs",
+		"  2022-01-01 old idea +someday conflict:ours",
+		"  2022-01-01 buy milk",
+		"  2022-01-01 buy stamps",
+		"",
+		"# Next",
+		"",
+		"  2022-01-01 water the plants",
+		"",
+		"# Logged",
+		"",
+		"x 2022-01-02 2022-01-01 call mom",
+	}, "\n") + "\n"
+
+	var trees [3]ast.TodoTxt
+	for i, lines := range [][]string{base, ours, theirs} {
+		var err error
+		trees[i], err = parseFile("", []byte(strings.Join(lines, "\n")))
+		if err != nil {
+			t.Fatalf("failed to parse input %d: %v", i, err)
+		}
+	}
+	merged, conflicts := mergeTodoTxt(trees[0], trees[1], trees[2])
+	if conflicts != 3 {
+		t.Errorf("want 3 conflicts, got %d", conflicts)
+	}
+	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
+	var got bytes.Buffer
+	if err := compileTodoTxt(merged, now).DumpText(&got); err != nil {
+		t.Fatalf("failed to dump merged file: %v", err)
+	}
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("mergeTodoTxt returned unexpected result (-got,+want):\n%s", diff)
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	table := []struct {
+		name         string
+		base         string
+		ours, theirs string
+		want         string
+		wantConflict bool
+	}{{
+		name:   "completed by us, edited by them",
+		base:   "# Next\n  2022-01-01 call mom",
+		ours:   "# Logged\nx 2022-01-02 2022-01-01 call mom",
+		theirs: "# Next\n  2022-01-01 call mom @phone due:2022-01-03",
+		want:   "# Logged\nx 2022-01-02 2022-01-01 call mom @phone due:2022-01-03\n",
+	}, {
+		name:   "edited by us, completed by them",
+		base:   "# Next\n  2022-01-01 call mom",
+		ours:   "# Today\n  2022-01-01 call mom +family",
+		theirs: "# Next\nx 2022-01-02 2022-01-01 call mom",
+		want:   "# Logged\n\nx 2022-01-02 2022-01-01 call mom +family\n",
+	}, {
+		name:         "completed and edited by us, edited by them",
+		base:         "# Next\n  2022-01-01 call mom",
+		ours:         "# Logged\nx 2022-01-02 2022-01-01 call mom @home",
+		theirs:       "# Next\n  2022-01-01 call mom @phone",
+		wantConflict: true,
+	}, {
+		name:   "ages are ignored",
+		base:   "# Next\n  2022-01-01 call mom age:20d",
+		ours:   "# Next\n  2022-01-01 call mom age:21d",
+		theirs: "# Next\n  2022-01-01 call mom @phone age:22d",
+		want:   "# Next\n  2022-01-01 call mom @phone\n",
+	}}
+	now := time.Date(2022, time.January, 02, 0, 0, 0, 0, time.UTC)
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			var trees [3]ast.TodoTxt
+			for i, input := range []string{tc.base, tc.ours, tc.theirs} {
+				var err error
+				if trees[i], err = parseFile("", []byte(input+"\n")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			merged, conflicts := mergeTodoTxt(trees[0], trees[1], trees[2])
+			if tc.wantConflict {
+				if conflicts != 1 {
+					t.Errorf("want a conflict, got %d", conflicts)
+				}
+				return
+			}
+			if conflicts != 0 {
+				t.Errorf("want no conflicts, got %d", conflicts)
+			}
+			var got bytes.Buffer
+			if err := compileTodoTxt(merged, now).DumpText(&got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got.String(), tc.want); diff != "" {
+				t.Errorf("unexpected merge (-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeTrivia(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, lines ...string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	base := write("base",
+		"<!-- keep me -->",
+		"",
+		"# Next",
+		"",
+		"> context note",
+		"",
+		"  2022-01-01 call mom",
+		"  2022-01-01 fix the printer",
+		"",
+		"> old note",
+	)
+	ours := write("ours",
+		"<!-- keep me -->",
+		"",
+		"# Next",
+		"",
+		"> context note",
+		"",
+		"  2022-01-01 call mom @phone",
+		"  2022-01-01 fix the printer",
+		"",
+		"> old note",
+	)
+	theirs := write("theirs",
+		"<!-- keep me -->",
+		"",
+		"# Next",
+		"",
+		"> context note",
+		"",
+		"  2022-01-01 call mom",
+		"",
+		"> their note",
+		"",
+		"  2022-01-01 fix the printer +office",
+	)
+	if err := runMerge([]string{"-now", "2022-01-01", base, ours, theirs}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"<!-- keep me -->",
+		"",
+		"# Next",
+		"",
+		"> context note",
+		"",
+		"  2022-01-01 call mom @phone",
+		"  2022-01-01 fix the printer +office",
+		"",
+		"> their note",
+	}, "\n") + "\n"
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("unexpected merge (-got,+want):\n%s", diff)
+	}
+}
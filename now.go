@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// clockFlags decide what time vogon thinks it is and which languages it reads
+// relative dates in. Every command registers them, so that runs can be pinned
+// to a time zone or made reproducible.
+type clockFlags struct {
+	tz       string
+	now      string
+	rollover int
+}
+
+func newClockFlags(fs *flag.FlagSet) *clockFlags {
+	c := new(clockFlags)
+	fs.StringVar(&c.tz, "tz", "", "Time zone to use, like America/New_York (default local time)")
+	fs.StringVar(&c.now, "now", "", "Pretend it is this time, as YYYY-MM-DD, YYYY-MM-DDTHH:MM, or RFC 3339")
+	fs.IntVar(&c.rollover, "rollover", 0, "Hour of the morning at which a new day starts")
+	fs.Func("locale", "Comma separated languages to read relative dates in, from de, en, es, and fr", func(names string) error {
+		locales, err := dates.LookupLocales(names)
+		if err != nil {
+			return err
+		}
+		dates.Active = locales
+		return nil
+	})
+	fs.Func("holidays", "File of holidays, either iCalendar or one YYYY-MM-DD date per line", func(path string) error {
+		holidays, err := dates.LoadHolidays(path)
+		if err != nil {
+			return err
+		}
+		dates.ActiveCalendar.Holidays = holidays
+		return nil
+	})
+	fs.BoolFunc("business-days", "Count +Nd offsets in working days, like +Nbd", func(value string) error {
+		on, err := strconv.ParseBool(value)
+		dates.ActiveCalendar.BusinessDays = on
+		return err
+	})
+	return c
+}
+
+// Now returns the current time in the configured time zone. Before the
+// rollover hour it is still considered to be the day before, unless -now
+// names a day outright.
+func (c *clockFlags) Now() (time.Time, error) {
+	if c.rollover < 0 || c.rollover > 23 {
+		return time.Time{}, fmt.Errorf("rollover hour %d must be between 0 and 23", c.rollover)
+	}
+
+	loc := time.Local
+	if c.tz != "" {
+		var err error
+		loc, err = time.LoadLocation(c.tz)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("bad time zone: %w", err)
+		}
+	}
+
+	if c.now == "" {
+		return time.Now().In(loc).Add(-time.Duration(c.rollover) * time.Hour), nil
+	}
+	if now, err := time.ParseInLocation(dateFmt, c.now, loc); err == nil {
+		return now, nil
+	}
+	if now, err := time.ParseInLocation(dateTimeFmt, c.now, loc); err == nil {
+		return now.Add(-time.Duration(c.rollover) * time.Hour), nil
+	}
+	if now, err := time.Parse(time.RFC3339, c.now); err == nil {
+		return now.In(loc).Add(-time.Duration(c.rollover) * time.Hour), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse -now %q, want YYYY-MM-DD, YYYY-MM-DDTHH:MM, or RFC 3339", c.now)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockFlagsNow(t *testing.T) {
+	table := []struct {
+		name    string
+		clock   clockFlags
+		want    string
+		wantErr bool
+	}{{
+		name:  "date",
+		clock: clockFlags{now: "2024-06-01", tz: "UTC"},
+		want:  "2024-06-01T00:00:00Z",
+	}, {
+		name:  "date ignores rollover",
+		clock: clockFlags{now: "2024-06-01", tz: "UTC", rollover: 4},
+		want:  "2024-06-01T00:00:00Z",
+	}, {
+		name:  "before rollover is yesterday",
+		clock: clockFlags{now: "2024-06-01T00:30", tz: "UTC", rollover: 4},
+		want:  "2024-05-31T20:30:00Z",
+	}, {
+		name:  "rfc3339 moves into time zone",
+		clock: clockFlags{now: "2024-06-01T00:30:00Z", tz: "America/Los_Angeles"},
+		want:  "2024-05-31T17:30:00-07:00",
+	}, {
+		name:    "bad time zone",
+		clock:   clockFlags{now: "2024-06-01", tz: "Nowhere/Special"},
+		wantErr: true,
+	}, {
+		name:    "bad rollover",
+		clock:   clockFlags{now: "2024-06-01", rollover: 24},
+		wantErr: true,
+	}, {
+		name:    "bad now",
+		clock:   clockFlags{now: "friday"},
+		wantErr: true,
+	}}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.clock.Now()
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("wantErr=%t, but got err=%v", tc.wantErr, err)
+			}
+			if tc.wantErr {
+				return
+			}
+			if got := got.Format(time.RFC3339); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,10 @@ package ast
 import (
 	"fmt"
 	"strings"
+
+	"github.com/spencer-p/vogon/pkg/dates"
+
+	"github.com/alecthomas/participle/v2/lexer"
 )
 
 type TodoTxt struct {
@@ -12,20 +16,37 @@ type TodoTxt struct {
 type Grouping struct {
 	Header []string `("#" @( Text+ ) Newline+)?`
 	Blocks []Block  `(@@ Newline*)*`
+
+	// Source is the header line as it was read, which is written back as
+	// long as the header has the same words.
+	Source string
 }
 
 type Block struct {
 	Children []*Entry `(@@ Newline?)+`
+
+	// Leading are the lines before the block's entries that are not
+	// entries: blank lines, comments, and prose. Blocks read from a file
+	// may have only these, like the text between the last entry under a
+	// header and the next header.
+	Leading []string
 }
 
 type Entry struct {
+	Pos lexer.Position
+
 	Header         string
 	Completed      bool               `@"x"?`
 	Priority       *string            `@Priority?`
-	CompletionDate *string            `(@Date`
-	CreationDate   *string            ` @Date | @Date)?`
+	CompletionDate *dates.Date        `(@Date`
+	CreationDate   *dates.Date        ` @Date | @Date)?`
 	Description    []*DescriptionPart `@@*`
 	Notes          []NoteLine         `@@*`
+
+	// Source are the lines the entry was read from, its own and then one
+	// per note, which are written back as long as the entry has the same
+	// words.
+	Source []string
 }
 
 type DescriptionPart struct {
@@ -36,7 +57,7 @@ type DescriptionPart struct {
 }
 
 type NoteLine struct {
-	Text []string `Newline "|" (@Text | @Tag)*`
+	Text []string `Newline "|" (@Text | @Tag | @Date)*`
 }
 
 type SpecialTag struct {
@@ -3,14 +3,46 @@ package ast
 import (
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 )
 
+// DumpText writes the entry and its notes. An entry that was parsed is
+// written as it was read, spacing and all, unless its words have changed since.
 func (e *Entry) DumpText(out io.Writer) error {
 	if e == nil {
 		return nil
 	}
+	var b strings.Builder
+	e.dumpCanonical(&b)
+	if e.sourceMatches(b.String()) {
+		for _, line := range e.Source {
+			if _, err := fmt.Fprintln(out, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// sourceMatches reports whether the lines the entry was read from have the
+// same words as canonical, the way the entry would be written now.
+func (e *Entry) sourceMatches(canonical string) bool {
+	lines := strings.Split(strings.TrimSuffix(canonical, "\n"), "\n")
+	if len(e.Source) != len(lines) {
+		return false
+	}
+	for i := range lines {
+		if !slices.Equal(strings.Fields(e.Source[i]), strings.Fields(lines[i])) {
+			return false
+		}
+	}
+	return true
+}
 
+func (e *Entry) dumpCanonical(out io.Writer) {
 	if e.Completed {
 		out.Write([]byte{'x'})
 	} else {
@@ -20,11 +52,30 @@ func (e *Entry) DumpText(out io.Writer) error {
 		fmt.Fprintf(out, " %s", *e.Priority)
 	}
 	if e.CompletionDate != nil {
-		fmt.Fprintf(out, " %s", *e.CompletionDate)
+		fmt.Fprintf(out, " %s", e.CompletionDate)
 	}
 	if e.CreationDate != nil {
-		fmt.Fprintf(out, " %s", *e.CreationDate)
+		fmt.Fprintf(out, " %s", e.CreationDate)
 	}
+	e.dumpDescription(out)
+	for _, line := range e.Notes {
+		out.Write([]byte("\n           |"))
+		for _, block := range line.Text {
+			fmt.Fprintf(out, " %s", block)
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+// DescriptionText returns the entry's description as it would be written,
+// without its completion mark, priority, or dates.
+func (e *Entry) DescriptionText() string {
+	var b strings.Builder
+	e.dumpDescription(&b)
+	return strings.TrimPrefix(b.String(), " ")
+}
+
+func (e *Entry) dumpDescription(out io.Writer) {
 	for _, p := range e.Description {
 		out.Write([]byte{' '})
 		switch {
@@ -47,50 +98,124 @@ func (e *Entry) DumpText(out io.Writer) error {
 			out.Write([]byte(p.SpecialTag.Value))
 		}
 	}
-	for _, line := range e.Notes {
-		out.Write([]byte("\n           |"))
-		for _, block := range line.Text {
-			fmt.Fprintf(out, " %s", block)
-		}
-	}
-	fmt.Fprintln(out)
-	return nil
 }
 
+// DumpText writes the file. Headers, entries, and the lines between them that
+// are not entries are written as they were read, where they still can be.
+// Blank lines are kept, but not the spaces on them. Sections are kept apart
+// by at least one, and the blank lines of blocks that have lost their
+// entries are only kept at the end of a section.
 func (t TodoTxt) DumpText(out io.Writer) error {
-	skipped := 0
-	for i, g := range t.Groupings {
-		if g.Len() == 0 {
-			skipped++
+	w := lineWriter{out: out}
+	for _, g := range t.Groupings {
+		if g.Len() == 0 && !g.hasText() {
 			continue
 		}
-		if i-skipped > 0 {
-			fmt.Fprintln(out)
+		header := strings.Join(g.Header, " ")
+		if header == "" && g.Len() > 0 && !w.started {
+			header = "Inbox"
 		}
-		if i-skipped == 0 && len(g.Header) == 0 {
-			g.Header = []string{"Inbox"}
+		// A header that was read from the file may have been followed by
+		// its entries without a blank line.
+		asRead := false
+		if header != "" {
+			w.separate()
+			if asRead = g.sourceMatches(); asRead {
+				w.line(g.Source)
+			} else {
+				w.line("# " + header)
+			}
 		}
-		fmt.Fprintf(out, "# %s\n\n", strings.Join(g.Header, " "))
-		for blockNum, b := range g.Blocks {
-			if len(b.Children) == 0 {
-				// Don't print out an empty block.
+		for i, b := range g.Blocks {
+			if len(b.Children) == 0 && (i+1 < len(g.Blocks) || len(b.Leading) == 0) && !hasText(b.Leading) {
+				// The header was still followed by a blank line.
+				asRead = asRead && len(b.Leading) == 0
 				continue
 			}
+			if len(b.Leading) > 0 {
+				for _, line := range b.Leading {
+					w.line(line)
+				}
+			} else if !asRead {
+				w.separate()
+			}
+			asRead = false
 			for _, e := range b.Children {
 				if e == nil {
 					continue
 				}
-				err := e.DumpText(out)
-				if err != nil {
-					return err
+				w.flush()
+				if w.err == nil {
+					w.err = e.DumpText(out)
 				}
 			}
+		}
+	}
+	return w.err
+}
 
-			if blockNum+1 < len(g.Blocks) {
-				fmt.Fprintf(out, "\n")
-			}
+// hasText reports whether any of the lines between the blocks of g are not
+// blank.
+func (g *Grouping) hasText() bool {
+	for _, b := range g.Blocks {
+		if hasText(b.Leading) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasText(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return true
 		}
 	}
+	return false
+}
+
+// sourceMatches reports whether the header line the grouping was read from
+// has the same words as its header.
+func (g *Grouping) sourceMatches() bool {
+	words := strings.Fields(strings.TrimPrefix(strings.TrimSpace(g.Source), "#"))
+	return g.Source != "" && slices.Equal(words, g.Header)
+}
+
+// lineWriter writes lines. Blank lines are held back until something else is
+// written, so that the file does not end in them.
+type lineWriter struct {
+	out     io.Writer
+	started bool
+	blanks  int
+	err     error
+}
+
+func (w *lineWriter) line(s string) {
+	if strings.TrimSpace(s) == "" {
+		w.blanks++
+		return
+	}
+	w.flush()
+	if w.err == nil {
+		_, w.err = fmt.Fprintln(w.out, s)
+	}
+}
+
+// separate makes sure there is a blank line before what is written next,
+// unless it is the first thing written.
+func (w *lineWriter) separate() {
+	if w.started && w.blanks == 0 {
+		w.blanks = 1
+	}
+}
 
-	return nil
+// flush writes the blank lines held back, before something else is written.
+func (w *lineWriter) flush() {
+	for ; w.blanks > 0 && w.started; w.blanks-- {
+		if w.err == nil {
+			_, w.err = fmt.Fprintln(w.out)
+		}
+	}
+	w.blanks = 0
+	w.started = true
 }
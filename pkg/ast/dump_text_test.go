@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+
+	"github.com/spencer-p/vogon/pkg/dates"
 )
 
-func ptr[T any](t T) *T {
-	return &t
+func date(s string) *dates.Date {
+	d, _ := dates.ParseDate(s)
+	return &d
 }
 
 func TestDumpEntry(t *testing.T) {
@@ -18,7 +21,7 @@ func TestDumpEntry(t *testing.T) {
 	}{{
 		name: "simple",
 		e: Entry{
-			CreationDate: ptr("2024-01-01"),
+			CreationDate: date("2024-01-01"),
 			Description: []*DescriptionPart{{
 				Text: []string{"hello", "world"},
 			}},
@@ -27,7 +30,7 @@ func TestDumpEntry(t *testing.T) {
 	}, {
 		name: "with notes",
 		e: Entry{
-			CreationDate: ptr("2024-01-01"),
+			CreationDate: date("2024-01-01"),
 			Description: []*DescriptionPart{{
 				Text: []string{"This is a title of sorts"},
 			}},
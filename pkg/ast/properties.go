@@ -1,6 +1,10 @@
 package ast
 
-import "time"
+import (
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/dates"
+)
 
 func (e *Entry) ScheduledFor() (date string, found bool) {
 	if e == nil {
@@ -42,6 +46,32 @@ func (e *Entry) Tag(key string) (value string, found bool) {
 	return
 }
 
+func (e *Entry) Projects() []string {
+	if e == nil {
+		return nil
+	}
+	var projects []string
+	for _, dp := range e.Description {
+		if dp.Project != nil {
+			projects = append(projects, *dp.Project)
+		}
+	}
+	return projects
+}
+
+func (e *Entry) Contexts() []string {
+	if e == nil {
+		return nil
+	}
+	var contexts []string
+	for _, dp := range e.Description {
+		if dp.Context != nil {
+			contexts = append(contexts, *dp.Context)
+		}
+	}
+	return contexts
+}
+
 func (e *Entry) RemoveTag(key string) {
 	if e == nil {
 		return
@@ -51,14 +81,20 @@ func (e *Entry) RemoveTag(key string) {
 	})
 }
 
+// CompletedWeek returns the ISO year and week the entry was completed in as
+// a single number, like 202419. It is 0 if there is no valid completion date.
 func (e *Entry) CompletedWeek() int {
-	if e.CompletionDate == nil {
+	if e.CompletionDate == nil || !e.CompletionDate.Valid() {
 		return 0
 	}
-	t, _ := time.Parse("2006-01-02", *e.CompletionDate)
-	year, week := t.ISOWeek()
+	year, week := e.CompletionDate.ISOWeek()
 	return year*100 + week
+}
 
+// Date returns the calendar day a date-valued tag refers to, like the
+// relative date in sched:fri or the time stamped date in due:2024-06-01T17:00.
+func (s *SpecialTag) Date(now time.Time) (dates.Date, error) {
+	return dates.ParseDay(now, s.Value)
 }
 
 func StringIsScheduled(str string) bool {
@@ -0,0 +1,184 @@
+package dates
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar knows which days are working days.
+type Calendar struct {
+	// Weekend are the days of the week nobody works, indexed by
+	// time.Weekday.
+	Weekend [7]bool
+	// Holidays are days off, by their YYYY-MM-DD form.
+	Holidays map[string]bool
+	// BusinessDays makes +Nd count working days, the same as +Nbd.
+	BusinessDays bool
+}
+
+// ActiveCalendar is the calendar ParseRelative counts working days with.
+var ActiveCalendar = &Calendar{
+	Weekend: [7]bool{time.Saturday: true, time.Sunday: true},
+}
+
+func (c *Calendar) IsHoliday(d Date) bool {
+	return c.Holidays[d.String()]
+}
+
+// IsWorkday reports whether d is neither on the weekend nor a holiday.
+func (c *Calendar) IsWorkday(d Date) bool {
+	return !c.Weekend[d.Weekday()] && !c.IsHoliday(d)
+}
+
+// NextWorkday returns the first working day after d.
+func (c *Calendar) NextWorkday(d Date) Date {
+	return c.AddWorkdays(d, 1)
+}
+
+// RollForward returns d if it is a working day, or else the next one.
+func (c *Calendar) RollForward(d Date) Date {
+	if c.IsWorkday(d) {
+		return d
+	}
+	return c.NextWorkday(d)
+}
+
+// AddWorkdays returns the date n working days after d. If n is negative, it
+// counts backwards.
+func (c *Calendar) AddWorkdays(d Date, n int) Date {
+	if !d.Valid() || c.allDaysOff() {
+		return d
+	}
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		d = d.AddDays(step)
+		if c.IsWorkday(d) {
+			n--
+		}
+	}
+	return d
+}
+
+func (c *Calendar) allDaysOff() bool {
+	for _, off := range c.Weekend {
+		if !off {
+			return false
+		}
+	}
+	return true
+}
+
+// parseOffset parses offsets from now like +3d, +2w, or +3bd, where bd
+// counts working days in cal.
+func parseOffset(cal *Calendar, now time.Time, offset string) (time.Time, bool) {
+	rest, found := strings.CutPrefix(offset, "+")
+	if !found {
+		return time.Time{}, false
+	}
+	unit := strings.TrimLeft(rest, "0123456789")
+	n, err := strconv.Atoi(rest[:len(rest)-len(unit)])
+	if err != nil {
+		return time.Time{}, false
+	}
+	if unit == "d" && cal.BusinessDays {
+		unit = "bd"
+	}
+	switch unit {
+	case "d":
+		return now.AddDate(0, 0, n), true
+	case "w":
+		return now.AddDate(0, 0, 7*n), true
+	case "bd":
+		today := DateOf(now)
+		days := today.DaysUntil(cal.AddWorkdays(today, n))
+		return now.AddDate(0, 0, days), true
+	}
+	return time.Time{}, false
+}
+
+// LoadHolidays reads holidays from a file, which is either an iCalendar
+// (.ics) file or a list of YYYY-MM-DD dates, one per line. In a list,
+// anything after the date is ignored, as are blank lines and lines starting
+// with #.
+func LoadHolidays(path string) (map[string]bool, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(contents, []byte("BEGIN:VCALENDAR")) {
+		return parseICS(contents)
+	}
+	return parseDateList(contents)
+}
+
+func parseDateList(contents []byte) (map[string]bool, error) {
+	holidays := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		d, err := ParseDate(strings.Fields(line)[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		holidays[d.String()] = true
+	}
+	return holidays, scanner.Err()
+}
+
+// parseICS collects the all day events of an iCalendar file. Recurring
+// events only count on their first day.
+func parseICS(contents []byte) (map[string]bool, error) {
+	holidays := make(map[string]bool)
+	var start, end Date
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";") // Drop parameters like VALUE=DATE.
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			start, end = Date{}, Date{}
+		case "DTSTART":
+			start = parseICSDate(value)
+		case "DTEND":
+			end = parseICSDate(value)
+		case "END":
+			if strings.ToUpper(value) != "VEVENT" || !start.Valid() {
+				continue
+			}
+			holidays[start.String()] = true
+			// DTEND is the day after an all day event ends.
+			for d := start.AddDays(1); end.Valid() && d.Before(end); d = d.AddDays(1) {
+				holidays[d.String()] = true
+			}
+		}
+	}
+	return holidays, scanner.Err()
+}
+
+// parseICSDate parses the date of an iCalendar DATE or DATE-TIME value, like
+// 20241225 or 20241225T000000Z.
+func parseICSDate(value string) Date {
+	if len(value) < len("20060102") {
+		return Date{}
+	}
+	t, err := time.Parse("20060102", value[:len("20060102")])
+	if err != nil {
+		return Date{}
+	}
+	return DateOf(t)
+}
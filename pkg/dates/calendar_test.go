@@ -0,0 +1,117 @@
+package dates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddWorkdays(t *testing.T) {
+	cal := &Calendar{
+		Weekend:  [7]bool{time.Saturday: true, time.Sunday: true},
+		Holidays: map[string]bool{"2024-12-25": true, "2024-12-26": true},
+	}
+	friday := mustParseDate(t, "2024-12-20")
+	table := []struct {
+		n    int
+		want string
+	}{
+		{0, "2024-12-20"},
+		{1, "2024-12-23"},
+		{3, "2024-12-27"},
+		{-1, "2024-12-19"},
+	}
+	for _, tc := range table {
+		if got := cal.AddWorkdays(friday, tc.n).String(); got != tc.want {
+			t.Errorf("AddWorkdays(%s, %d) = %s, want %s", friday, tc.n, got, tc.want)
+		}
+	}
+	if got := cal.RollForward(mustParseDate(t, "2024-12-25")).String(); got != "2024-12-27" {
+		t.Errorf("RollForward(2024-12-25) = %s, want 2024-12-27", got)
+	}
+	if got := cal.RollForward(friday); !got.Equal(friday) {
+		t.Errorf("RollForward(%s) = %s, want it unchanged", friday, got)
+	}
+}
+
+func TestParseRelativeOffsets(t *testing.T) {
+	defer func(cal *Calendar) { ActiveCalendar = cal }(ActiveCalendar)
+	ActiveCalendar = &Calendar{
+		Weekend:  [7]bool{time.Saturday: true, time.Sunday: true},
+		Holidays: map[string]bool{"2024-12-25": true},
+	}
+
+	friday := time.Date(2024, time.December, 20, 9, 0, 0, 0, time.UTC)
+	table := []struct {
+		date         string
+		businessDays bool
+		want         string
+	}{
+		{date: "+3d", want: "2024-12-23"},
+		{date: "+3d", businessDays: true, want: "2024-12-26"},
+		{date: "+3bd", want: "2024-12-26"},
+		{date: "+2w", want: "2025-01-03"},
+		{date: "next-workday", want: "2024-12-23"},
+	}
+	for _, tc := range table {
+		ActiveCalendar.BusinessDays = tc.businessDays
+		got, err := ParseRelative(friday, tc.date)
+		if err != nil {
+			t.Errorf("ParseRelative(%q) failed: %v", tc.date, err)
+			continue
+		}
+		if got.Format(DateFormat) != tc.want {
+			t.Errorf("ParseRelative(%q) with business days %t = %s, want %s",
+				tc.date, tc.businessDays, got.Format(DateFormat), tc.want)
+		}
+	}
+}
+
+func TestLoadHolidays(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "holidays.txt")
+	os.WriteFile(list, []byte("# Office closures\n2024-12-25 Christmas\n\n2025-01-01\n"), 0644)
+	ics := filepath.Join(dir, "holidays.ics")
+	os.WriteFile(ics, []byte(`BEGIN:VCALENDAR
+BEGIN:VEVENT
+SUMMARY:Winter break
+DTSTART;VALUE=DATE:20241224
+DTEND;VALUE=DATE:20241227
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:New Year
+DTSTART:20250101T000000Z
+END:VEVENT
+END:VCALENDAR
+`), 0644)
+
+	table := []struct {
+		path string
+		want []string
+	}{
+		{list, []string{"2024-12-25", "2025-01-01"}},
+		{ics, []string{"2024-12-24", "2024-12-25", "2024-12-26", "2025-01-01"}},
+	}
+	for _, tc := range table {
+		got, err := LoadHolidays(tc.path)
+		if err != nil {
+			t.Errorf("LoadHolidays(%s) failed: %v", filepath.Base(tc.path), err)
+			continue
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("LoadHolidays(%s) = %v, want %v", filepath.Base(tc.path), got, tc.want)
+		}
+		for _, day := range tc.want {
+			if !got[day] {
+				t.Errorf("LoadHolidays(%s) is missing %s", filepath.Base(tc.path), day)
+			}
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.txt")
+	os.WriteFile(bad, []byte("2024-02-30\n"), 0644)
+	if _, err := LoadHolidays(bad); err == nil {
+		t.Errorf("LoadHolidays should reject 2024-02-30")
+	}
+}
@@ -0,0 +1,30 @@
+package dates
+
+import "time"
+
+// Day returns midnight at the start of t's calendar day, in t's location.
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// CompareDays compares the calendar days of a and b, each in its own
+// location. It returns -1 if a's day is before b's, 0 if they are the same
+// day, and 1 otherwise.
+func CompareDays(a, b time.Time) int {
+	switch days := DaysBetween(b, a); {
+	case days < 0:
+		return -1
+	case days > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// DaysBetween returns the number of calendar days from a's day to b's day,
+// each in its own location. It is not thrown off by daylight saving time.
+func DaysBetween(a, b time.Time) int {
+	civilA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	civilB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(civilB.Sub(civilA).Hours() / 24)
+}
@@ -0,0 +1,56 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysBetween(t *testing.T) {
+	nyc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	table := []struct {
+		name string
+		a, b time.Time
+		want int
+	}{{
+		name: "same day",
+		a:    time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		b:    time.Date(2024, time.March, 1, 23, 59, 0, 0, time.UTC),
+		want: 0,
+	}, {
+		name: "across daylight saving",
+		a:    time.Date(2024, time.March, 9, 12, 0, 0, 0, nyc),
+		b:    time.Date(2024, time.March, 11, 0, 30, 0, 0, nyc),
+		want: 2,
+	}, {
+		name: "calendar days in different zones",
+		a:    time.Date(2024, time.March, 1, 23, 0, 0, 0, nyc), // Already March 2 in UTC.
+		b:    time.Date(2024, time.March, 2, 1, 0, 0, 0, time.UTC),
+		want: 1,
+	}, {
+		name: "backwards",
+		a:    time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		b:    time.Date(2023, time.December, 25, 0, 0, 0, 0, time.UTC),
+		want: -7,
+	}}
+	for _, tc := range table {
+		if got := DaysBetween(tc.a, tc.b); got != tc.want {
+			t.Errorf("%s: DaysBetween() = %d, want %d", tc.name, got, tc.want)
+		}
+		if got, want := CompareDays(tc.b, tc.a), sign(tc.want); got != want {
+			t.Errorf("%s: CompareDays() = %d, want %d", tc.name, got, want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
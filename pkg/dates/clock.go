@@ -0,0 +1,113 @@
+package dates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const clockFmt = "15:04"
+
+// CutTime splits a date value into its date and time of day. The time may be
+// written after an "@" or an ISO "T", like fri@14:00 or 2024-06-01T17:00.
+// If there is no time of day, CutTime returns value, "", false.
+func CutTime(value string) (date, clock string, found bool) {
+	i := strings.LastIndexAny(value, "@T")
+	if i <= 0 {
+		return value, "", false
+	}
+	if _, err := ParseClock(value[i+1:]); err != nil {
+		return value, "", false
+	}
+	return value[:i], value[i+1:], true
+}
+
+// ParseClock parses a time of day like 9:30, 14:00, 17, or 2pm and returns
+// its offset from midnight.
+func ParseClock(clock string) (time.Duration, error) {
+	lower := strings.ToLower(clock)
+	meridiem := ""
+	if rest, found := strings.CutSuffix(lower, "am"); found {
+		lower, meridiem = rest, "am"
+	} else if rest, found := strings.CutSuffix(lower, "pm"); found {
+		lower, meridiem = rest, "pm"
+	}
+
+	hourStr, minStr, hasMin := strings.Cut(lower, ":")
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || len(hourStr) > 2 {
+		return 0, fmt.Errorf("bad hour in time %q", clock)
+	}
+	min := 0
+	if hasMin {
+		min, err = strconv.Atoi(minStr)
+		if err != nil || len(minStr) != 2 {
+			return 0, fmt.Errorf("bad minutes in time %q", clock)
+		}
+	}
+	if meridiem != "" {
+		// 12am is midnight and 12pm is noon.
+		if hour < 1 || hour > 12 {
+			return 0, fmt.Errorf("bad hour in time %q", clock)
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+	}
+	if hour < 0 || hour > 23 || min < 0 || min > 59 {
+		return 0, fmt.Errorf("time %q out of range", clock)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute, nil
+}
+
+// ParseClockRange parses a time of day or a range of times like
+// 9:30-10:00. A single time is returned as both the start and end.
+func ParseClockRange(clocks string) (start, end time.Duration, err error) {
+	startStr, endStr, isRange := strings.Cut(clocks, "-")
+	start, err = ParseClock(startStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err = ParseClock(endStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("time range %q ends before it starts", clocks)
+	}
+	return start, end, nil
+}
+
+// FormatClock formats an offset from midnight as HH:MM.
+func FormatClock(clock time.Duration) string {
+	return time.Time{}.Add(clock).Format(clockFmt)
+}
+
+// ParseRelativeTime is ParseRelative for values that may carry a time of
+// day. It also accepts absolute YYYY-MM-DD dates. It reports whether a time
+// of day was given.
+func ParseRelativeTime(now time.Time, value string) (time.Time, bool, error) {
+	date, clock, hasClock := CutTime(value)
+	day, err := ParseRelative(now, date)
+	if err != nil {
+		absolute, absErr := ParseDate(date)
+		if absErr != nil {
+			if len(date) > 0 && date[0] >= '0' && date[0] <= '9' {
+				err = absErr // It was meant to be YYYY-MM-DD.
+			}
+			return time.Time{}, false, err
+		}
+		day = absolute.Time(now.Location())
+	}
+	var offset time.Duration
+	if hasClock {
+		offset, _ = ParseClock(clock) // Already checked by CutTime.
+	}
+	hour, min := int(offset/time.Hour), int(offset%time.Hour/time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, min, 0, 0, day.Location()), hasClock, nil
+}
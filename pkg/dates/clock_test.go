@@ -0,0 +1,83 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCutTime(t *testing.T) {
+	table := []struct {
+		value     string
+		date      string
+		clock     string
+		wantFound bool
+	}{
+		{"fri@14:00", "fri", "14:00", true},
+		{"2024-06-01T17:00", "2024-06-01", "17:00", true},
+		{"t@9", "t", "9", true},
+		{"tomorrow", "tomorrow", "", false},
+		{"nextThu", "nextThu", "", false},
+		{"2024-06-01", "2024-06-01", "", false},
+		{"@14:00", "@14:00", "", false},
+	}
+	for _, tc := range table {
+		date, clock, found := CutTime(tc.value)
+		if date != tc.date || clock != tc.clock || found != tc.wantFound {
+			t.Errorf("CutTime(%q) = %q, %q, %t, want %q, %q, %t",
+				tc.value, date, clock, found, tc.date, tc.clock, tc.wantFound)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	table := []struct {
+		clock   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{clock: "14:00", want: 14 * time.Hour},
+		{clock: "9:30", want: 9*time.Hour + 30*time.Minute},
+		{clock: "17", want: 17 * time.Hour},
+		{clock: "2pm", want: 14 * time.Hour},
+		{clock: "12:15am", want: 15 * time.Minute},
+		{clock: "12pm", want: 12 * time.Hour},
+		{clock: "24:00", wantErr: true},
+		{clock: "9:5", wantErr: true},
+		{clock: "13pm", wantErr: true},
+		{clock: "noon", wantErr: true},
+	}
+	for _, tc := range table {
+		got, err := ParseClock(tc.clock)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("ParseClock(%q) wantErr=%t, but got err=%v", tc.clock, tc.wantErr, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseClock(%q) = %s, want %s", tc.clock, got, tc.want)
+		}
+	}
+}
+
+func TestParseRelativeTime(t *testing.T) {
+	now := time.Date(2022, time.January, 1, 8, 0, 0, 0, time.UTC)
+	table := []struct {
+		value     string
+		want      time.Time
+		wantClock bool
+	}{
+		{"fri@14:00", time.Date(2022, time.January, 7, 14, 0, 0, 0, time.UTC), true},
+		{"2022-03-04T9:15", time.Date(2022, time.March, 4, 9, 15, 0, 0, time.UTC), true},
+		{"tomorrow", time.Date(2022, time.January, 2, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tc := range table {
+		got, hasClock, err := ParseRelativeTime(now, tc.value)
+		if err != nil {
+			t.Errorf("ParseRelativeTime(%q) failed: %v", tc.value, err)
+			continue
+		}
+		if !got.Equal(tc.want) || hasClock != tc.wantClock {
+			t.Errorf("ParseRelativeTime(%q) = %s, %t, want %s, %t",
+				tc.value, got, hasClock, tc.want, tc.wantClock)
+		}
+	}
+}
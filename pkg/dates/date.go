@@ -0,0 +1,207 @@
+package dates
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DateFormat is the layout of a Date written as text.
+const DateFormat = "2006-01-02"
+
+// Date is a calendar day, without a time of day or a location. The zero
+// Date is not a valid day.
+//
+// Text that is shaped like a date but does not name a real day, like
+// 2024-02-30, can still be held in a Date so that it is written back
+// unchanged. Such dates are not Valid, sort after every valid date, and
+// report why through Err.
+type Date struct {
+	year  int
+	month time.Month
+	day   int
+
+	invalid string
+	err     error
+}
+
+// NewDate returns the date for the given day. Out of range values are
+// normalized the same way as time.Date, so NewDate(2024, 2, 30) is March 1.
+func NewDate(year int, month time.Month, day int) Date {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// DateOf returns the calendar day of t in t's location.
+func DateOf(t time.Time) Date {
+	return Date{year: t.Year(), month: t.Month(), day: t.Day()}
+}
+
+// ParseDate parses a YYYY-MM-DD date, rejecting days that do not exist.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateFormat, s)
+	if err != nil {
+		var parseErr *time.ParseError
+		if errors.As(err, &parseErr) && parseErr.Message != "" {
+			// The layout matched, but the day does not exist.
+			return Date{}, fmt.Errorf("invalid date %q%s", s, parseErr.Message)
+		}
+		return Date{}, fmt.Errorf("invalid date %q, want YYYY-MM-DD", s)
+	}
+	if t.Year() == 0 {
+		// The zero Date stands for no date at all.
+		return Date{}, fmt.Errorf("invalid date %q: there is no year 0", s)
+	}
+	return DateOf(t), nil
+}
+
+// ParseDay returns the calendar day of a relative or YYYY-MM-DD date value,
+// like the value of a due: tag. Any time of day is ignored.
+func ParseDay(now time.Time, value string) (Date, error) {
+	t, _, err := ParseRelativeTime(now, value)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+// UnmarshalText parses a YYYY-MM-DD date. Unlike ParseDate it never fails;
+// an invalid date is kept along with its error.
+func (d *Date) UnmarshalText(text []byte) error {
+	parsed, err := ParseDate(string(text))
+	if err != nil {
+		*d = Date{invalid: string(text), err: err}
+		return nil
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Valid reports whether d is a real calendar day.
+func (d Date) Valid() bool {
+	return d.invalid == "" && d.year != 0
+}
+
+// Err explains why d is not valid, or returns nil if it is.
+func (d Date) Err() error {
+	switch {
+	case d.err != nil:
+		return d.err
+	case !d.Valid():
+		return fmt.Errorf("missing date")
+	}
+	return nil
+}
+
+// String formats d as YYYY-MM-DD, or returns the original text of an
+// invalid date.
+func (d Date) String() string {
+	if d.invalid != "" {
+		return d.invalid
+	}
+	if !d.Valid() {
+		return ""
+	}
+	return d.Time(time.UTC).Format(DateFormat)
+}
+
+// Time returns midnight at the start of d in loc.
+func (d Date) Time(loc *time.Location) time.Time {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, loc)
+}
+
+// Compare returns -1 if d is before other, 0 if they are the same day, and 1
+// if d is after other. Invalid dates come after all valid dates and are
+// ordered by their text.
+func (d Date) Compare(other Date) int {
+	switch {
+	case d.Valid() && !other.Valid():
+		return -1
+	case !d.Valid() && other.Valid():
+		return 1
+	case !d.Valid() && !other.Valid():
+		return compareStrings(d.invalid, other.invalid)
+	}
+	if c := compareInts(d.year, other.year); c != 0 {
+		return c
+	}
+	if c := compareInts(int(d.month), int(other.month)); c != 0 {
+		return c
+	}
+	return compareInts(d.day, other.day)
+}
+
+func (d Date) Before(other Date) bool { return d.Compare(other) < 0 }
+func (d Date) After(other Date) bool  { return d.Compare(other) > 0 }
+func (d Date) Equal(other Date) bool  { return d.Compare(other) == 0 }
+
+// AddDays returns the date n days after d. Invalid dates are returned as is.
+func (d Date) AddDays(n int) Date {
+	if !d.Valid() {
+		return d
+	}
+	return DateOf(d.Time(time.UTC).AddDate(0, 0, n))
+}
+
+// AddMonths returns the date n months after d, normalized like
+// time.AddDate. Invalid dates are returned as is.
+func (d Date) AddMonths(n int) Date {
+	if !d.Valid() {
+		return d
+	}
+	return DateOf(d.Time(time.UTC).AddDate(0, n, 0))
+}
+
+// DaysUntil returns the number of days from d to other, which is negative if
+// other is before d. It is zero if either date is invalid.
+func (d Date) DaysUntil(other Date) int {
+	if !d.Valid() || !other.Valid() {
+		return 0
+	}
+	return DaysBetween(d.Time(time.UTC), other.Time(time.UTC))
+}
+
+func (d Date) Weekday() time.Weekday {
+	return d.Time(time.UTC).Weekday()
+}
+
+// ISOWeek returns the ISO 8601 year and week number of d.
+func (d Date) ISOWeek() (year, week int) {
+	return d.Time(time.UTC).ISOWeek()
+}
+
+// StartOfWeek returns the Monday on or before d.
+func (d Date) StartOfWeek() Date {
+	return d.AddDays(-(int(d.Weekday()) + 6) % 7)
+}
+
+// StartOfMonth returns the first day of d's month.
+func (d Date) StartOfMonth() Date {
+	if !d.Valid() {
+		return d
+	}
+	return Date{year: d.year, month: d.month, day: 1}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParseDate(t *testing.T, s string) Date {
+	t.Helper()
+	d, err := ParseDate(s)
+	if err != nil {
+		t.Fatalf("ParseDate(%q) failed: %v", s, err)
+	}
+	return d
+}
+
+func TestParseDate(t *testing.T) {
+	table := []struct {
+		text    string
+		wantErr bool
+	}{
+		{text: "2024-02-29"},
+		{text: "2024-02-30", wantErr: true},
+		{text: "2023-02-29", wantErr: true},
+		{text: "2025-22-98", wantErr: true},
+		{text: "2024-6-1", wantErr: true},
+		{text: "0000-01-01", wantErr: true},
+		{text: "friday", wantErr: true},
+	}
+	for _, tc := range table {
+		d, err := ParseDate(tc.text)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("ParseDate(%q) wantErr=%t, but got err=%v", tc.text, tc.wantErr, err)
+			continue
+		}
+		if err == nil && d.String() != tc.text {
+			t.Errorf("ParseDate(%q).String() = %q", tc.text, d.String())
+		}
+	}
+}
+
+func TestUnmarshalTextKeepsInvalidDates(t *testing.T) {
+	var d Date
+	if err := d.UnmarshalText([]byte("2024-02-30")); err != nil {
+		t.Fatalf("UnmarshalText failed: %v", err)
+	}
+	if d.Valid() {
+		t.Errorf("2024-02-30 should not be valid")
+	}
+	if d.Err() == nil {
+		t.Errorf("2024-02-30 should have an error")
+	}
+	if got := d.String(); got != "2024-02-30" {
+		t.Errorf("got %q, want the original text", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	var invalid Date
+	invalid.UnmarshalText([]byte("2024-02-30"))
+	ordered := []Date{
+		mustParseDate(t, "2023-12-31"),
+		mustParseDate(t, "2024-01-01"),
+		mustParseDate(t, "2024-01-02"),
+		mustParseDate(t, "2024-02-01"),
+		invalid,
+	}
+	for i := range ordered {
+		for j := range ordered {
+			want := compareInts(i, j)
+			if got := ordered[i].Compare(ordered[j]); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	d := mustParseDate(t, "2024-02-28")
+	if got := d.AddDays(2).String(); got != "2024-03-01" {
+		t.Errorf("AddDays(2) = %s, want 2024-03-01", got)
+	}
+	if got := d.AddMonths(-3).String(); got != "2023-11-28" {
+		t.Errorf("AddMonths(-3) = %s, want 2023-11-28", got)
+	}
+	if got := d.DaysUntil(mustParseDate(t, "2025-02-28")); got != 366 {
+		t.Errorf("DaysUntil a year later = %d, want 366", got)
+	}
+	if got := d.StartOfWeek().String(); got != "2024-02-26" {
+		t.Errorf("StartOfWeek() = %s, want 2024-02-26", got)
+	}
+	if got := d.StartOfMonth().String(); got != "2024-02-01" {
+		t.Errorf("StartOfMonth() = %s, want 2024-02-01", got)
+	}
+	if year, week := mustParseDate(t, "2024-12-30").ISOWeek(); year != 2025 || week != 1 {
+		t.Errorf("ISOWeek() = %d-W%02d, want 2025-W01", year, week)
+	}
+}
+
+func TestDateOfUsesLocation(t *testing.T) {
+	nyc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	// Already March 2 in UTC, but still March 1 in New York.
+	late := time.Date(2024, time.March, 1, 23, 0, 0, 0, nyc)
+	if got := DateOf(late).String(); got != "2024-03-01" {
+		t.Errorf("DateOf() = %s, want 2024-03-01", got)
+	}
+}
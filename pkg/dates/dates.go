@@ -2,7 +2,6 @@
 package dates
 
 import (
-	"fmt"
 	"strings"
 	"time"
 )
@@ -27,40 +26,25 @@ var (
 	Sunday    = weekday(time.Sunday)
 )
 
-func cutPrefix(str, prefix string) (string, bool) {
-	if len(str) < len(prefix) {
-		return "", false
+// ParseRelative parses a relative date like "tomorrow", "fri", or
+// "nextmonday" in any of the Active locales. It also understands offsets like
+// +3d, +2w, or +3bd, and next-workday, which count working days in the
+// ActiveCalendar.
+func ParseRelative(now time.Time, date string) (time.Time, error) {
+	date = strings.ToLower(date)
+	if offset, ok := parseOffset(ActiveCalendar, now, date); ok {
+		return offset, nil
 	}
-	if str[:len(prefix)] != prefix {
-		return "", false
+	if date == "next-workday" || date == "nextworkday" {
+		today := DateOf(now)
+		return now.AddDate(0, 0, today.DaysUntil(ActiveCalendar.NextWorkday(today))), nil
 	}
-	return str[len(prefix):], true
-}
-
-func ParseRelative(now time.Time, date string) (time.Time, error) {
-	if date, found := CutPrefix(date, "next"); found {
-		return ParseRelative(now.AddDate(0, 0, 7), date)
+	if rest, found := cutNext(Active, date); found {
+		return ParseRelative(now.AddDate(0, 0, 7), rest)
 	}
-	switch strings.ToLower(date) {
-	case "tomorrow", "tom":
-		return now.AddDate(0, 0, 1), nil
-	case "monday", "mon":
-		return Monday(now), nil
-	case "tuesday", "tue":
-		return Tuesday(now), nil
-	case "wednesday", "wed":
-		return Wednesday(now), nil
-	case "thursday", "thu":
-		return Thursday(now), nil
-	case "friday", "fri":
-		return Friday(now), nil
-	case "saturday", "sat":
-		return Saturday(now), nil
-	case "sunday", "sun":
-		return Sunday(now), nil
-	case "today", "tod":
-		return now, nil
-	default:
-		return time.Time{}, fmt.Errorf("unknown datestring: %q", date)
+	day, err := lookup(Active, date)
+	if err != nil {
+		return time.Time{}, err
 	}
+	return day.from(now), nil
 }
@@ -0,0 +1,68 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRelative(t *testing.T) {
+	defer func(active []*Locale) { Active = active }(Active)
+
+	// A Wednesday.
+	now := time.Date(2024, time.June, 5, 0, 0, 0, 0, time.UTC)
+	table := []struct {
+		locales string
+		date    string
+		want    string
+		wantErr bool
+	}{
+		{date: "today", want: "2024-06-05"},
+		{date: "tod", want: "2024-06-05"},
+		{date: "tom", want: "2024-06-06"},
+		{date: "fri", want: "2024-06-07"},
+		{date: "Friday", want: "2024-06-07"},
+		{date: "th", want: "2024-06-06"},
+		{date: "wed", want: "2024-06-12"},
+		{date: "nextfri", want: "2024-06-14"},
+		{date: "t", wantErr: true},
+		{date: "s", wantErr: true},
+		{date: "next", wantErr: true},
+		{date: "someday", wantErr: true},
+		{locales: "de", date: "morgen", want: "2024-06-06"},
+		{locales: "de", date: "freitag", want: "2024-06-07"},
+		{locales: "de", date: "nächstermontag", want: "2024-06-17"},
+		{locales: "de", date: "mo", wantErr: true}, // montag or morgen?
+		{locales: "de", date: "mon", want: "2024-06-10"},
+		{locales: "fr", date: "vendredi", want: "2024-06-07"},
+		{locales: "fr", date: "lundiprochain", want: "2024-06-17"},
+		{locales: "fr", date: "demain", want: "2024-06-06"},
+		{locales: "es", date: "mañana", want: "2024-06-06"},
+		{locales: "es", date: "sábado", want: "2024-06-08"},
+		{locales: "es", date: "sab", want: "2024-06-08"},
+		{locales: "es,fr", date: "mar", want: "2024-06-11"}, // martes and mardi agree.
+		{locales: "es,fr", date: "ma", wantErr: true},       // mañana disagrees.
+		{locales: "fr", date: "today", want: "2024-06-05"},  // English is always on.
+	}
+	for _, tc := range table {
+		locales, err := LookupLocales(tc.locales)
+		if err != nil {
+			t.Fatalf("LookupLocales(%q) failed: %v", tc.locales, err)
+		}
+		Active = locales
+
+		got, err := ParseRelative(now, tc.date)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("ParseRelative(%q) in %q wantErr=%t, but got %s, %v", tc.date, tc.locales, tc.wantErr, got, err)
+			continue
+		}
+		if err == nil && got.Format(DateFormat) != tc.want {
+			t.Errorf("ParseRelative(%q) in %q = %s, want %s", tc.date, tc.locales, got.Format(DateFormat), tc.want)
+		}
+	}
+}
+
+func TestLookupLocalesRejectsUnknown(t *testing.T) {
+	if _, err := LookupLocales("de,xx"); err == nil {
+		t.Errorf("LookupLocales should reject unknown locales")
+	}
+}
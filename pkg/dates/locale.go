@@ -0,0 +1,174 @@
+package dates
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Locale holds the words a language uses for relative dates. Words are
+// lower case, and a date may be any unambiguous prefix of one.
+type Locale struct {
+	Name     string
+	Today    []string
+	Tomorrow []string
+	// Next words push a date out by a week. They may come before or after
+	// the rest of the date, as in nextfriday or vendrediprochain.
+	Next []string
+	// Weekdays are the names of each day, indexed by time.Weekday.
+	Weekdays [7][]string
+}
+
+var (
+	English = &Locale{
+		Name:     "en",
+		Today:    []string{"today"},
+		Tomorrow: []string{"tomorrow"},
+		Next:     []string{"next"},
+		Weekdays: [7][]string{
+			{"sunday"}, {"monday"}, {"tuesday"}, {"wednesday"},
+			{"thursday"}, {"friday"}, {"saturday"},
+		},
+	}
+	German = &Locale{
+		Name:     "de",
+		Today:    []string{"heute"},
+		Tomorrow: []string{"morgen"},
+		Next:     []string{"nächster", "nächsten", "nächste", "naechster", "naechsten", "naechste"},
+		Weekdays: [7][]string{
+			{"sonntag"}, {"montag"}, {"dienstag"}, {"mittwoch"},
+			{"donnerstag"}, {"freitag"}, {"samstag", "sonnabend"},
+		},
+	}
+	French = &Locale{
+		Name:     "fr",
+		Today:    []string{"aujourd'hui", "aujourdhui"},
+		Tomorrow: []string{"demain"},
+		Next:     []string{"prochain", "prochaine"},
+		Weekdays: [7][]string{
+			{"dimanche"}, {"lundi"}, {"mardi"}, {"mercredi"},
+			{"jeudi"}, {"vendredi"}, {"samedi"},
+		},
+	}
+	Spanish = &Locale{
+		Name:     "es",
+		Today:    []string{"hoy"},
+		Tomorrow: []string{"mañana", "manana"},
+		Next:     []string{"próximo", "proximo", "próxima", "proxima"},
+		Weekdays: [7][]string{
+			{"domingo"}, {"lunes"}, {"martes"}, {"miércoles", "miercoles"},
+			{"jueves"}, {"viernes"}, {"sábado", "sabado"},
+		},
+	}
+)
+
+// Locales are the built in locales by name.
+var Locales = map[string]*Locale{
+	English.Name: English,
+	German.Name:  German,
+	French.Name:  French,
+	Spanish.Name: Spanish,
+}
+
+// Active are the locales ParseRelative understands.
+var Active = []*Locale{English}
+
+// LookupLocales parses a comma separated list of locale names, like "de,fr".
+// English is always understood, so it is added to the end if missing.
+func LookupLocales(names string) ([]*Locale, error) {
+	var result []*Locale
+	hasEnglish := false
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		l, ok := Locales[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown locale %q", name)
+		}
+		hasEnglish = hasEnglish || l == English
+		result = append(result, l)
+	}
+	if !hasEnglish {
+		result = append(result, English)
+	}
+	return result, nil
+}
+
+// relativeDay is what a word means: a weekday, or one of the values below.
+type relativeDay int
+
+const (
+	today relativeDay = iota + 7
+	tomorrow
+)
+
+func (r relativeDay) from(now time.Time) time.Time {
+	switch r {
+	case today:
+		return now
+	case tomorrow:
+		return now.AddDate(0, 0, 1)
+	default:
+		return weekday(time.Weekday(r))(now)
+	}
+}
+
+// lookup finds the meaning of word in the locales. An exact match wins;
+// otherwise word must be a prefix of words that all mean the same thing.
+func lookup(locales []*Locale, word string) (relativeDay, error) {
+	if word == "" {
+		return 0, fmt.Errorf("empty datestring")
+	}
+	meanings := make(map[relativeDay]bool)
+	var candidates []string
+	for _, l := range locales {
+		for meaning, words := range l.meanings() {
+			for _, w := range words {
+				if w == word {
+					return meaning, nil
+				}
+				if strings.HasPrefix(w, word) {
+					meanings[meaning] = true
+					candidates = append(candidates, w)
+				}
+			}
+		}
+	}
+	switch len(meanings) {
+	case 0:
+		return 0, fmt.Errorf("unknown datestring: %q", word)
+	case 1:
+		for meaning := range meanings {
+			return meaning, nil
+		}
+	}
+	return 0, fmt.Errorf("ambiguous datestring %q could be any of %s", word, strings.Join(candidates, ", "))
+}
+
+func (l *Locale) meanings() map[relativeDay][]string {
+	m := map[relativeDay][]string{
+		today:    l.Today,
+		tomorrow: l.Tomorrow,
+	}
+	for day, words := range l.Weekdays {
+		m[relativeDay(day)] = words
+	}
+	return m
+}
+
+// cutNext removes a word meaning "next" from the start or end of date.
+func cutNext(locales []*Locale, date string) (string, bool) {
+	for _, l := range locales {
+		for _, next := range l.Next {
+			if rest, found := strings.CutPrefix(date, next); found && rest != "" {
+				return rest, true
+			}
+			if rest, found := strings.CutSuffix(date, next); found && rest != "" {
+				return rest, true
+			}
+		}
+	}
+	return date, false
+}
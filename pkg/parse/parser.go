@@ -0,0 +1,276 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Parse parses a todo file a line at a time. It reads the language of the
+// grammar in pkg/ast, which BuildParser builds a parser for, and produces the
+// same tree for the files vogon writes.
+//
+// It is more forgiving than the grammar where that grammar loses text. Notes
+// keep every word, where the grammar would start a new entry at a date,
+// priority, project, context, or "#". Dates and priorities in the middle of a
+// description are words rather than the start of another entry. URLs are
+// words rather than tags, and headers may have any words in them.
+//
+// It also keeps what the grammar throws away, so that the file can be written
+// back the way it was: the lines entries and headers were read from, blank
+// lines, and lines that are not entries. Those are markdown quotes and
+// subheadings, lines starting with ">" or "##", and comments between "<!--"
+// and "-->", which may span lines.
+func Parse(filename string, input []byte) (ast.TodoTxt, error) {
+	return ParseReader(filename, bytes.NewReader(input))
+}
+
+// ParseReader is Parse for a stream.
+func ParseReader(filename string, r io.Reader) (ast.TodoTxt, error) {
+	p := parser{
+		r:   bufio.NewReader(r),
+		pos: lexer.Position{Filename: filename, Line: 1, Column: 1},
+	}
+	for {
+		line, err := p.r.ReadString('\n')
+		if len(line) > 0 {
+			text := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if err := p.parseLine(text); err != nil {
+				return p.t, err
+			}
+			p.pos.Offset += len(line)
+			p.pos.Line++
+		}
+		if err == io.EOF {
+			p.endGrouping()
+			return p.t, nil
+		}
+		if err != nil {
+			return p.t, err
+		}
+	}
+}
+
+type parser struct {
+	r   *bufio.Reader
+	pos lexer.Position // Start of the current line.
+
+	t ast.TodoTxt
+	// block is the block entries are added to, or nil if the next entry
+	// starts a new one.
+	block *ast.Block
+	// entry is the entry notes are added to, or nil if a note would be an
+	// entry of its own.
+	entry *ast.Entry
+	// leading are the lines since the last entry that are not entries, which
+	// go before the next block.
+	leading []string
+	// inComment is set while reading a comment that spans lines.
+	inComment bool
+}
+
+// word is a run of text between spaces, and where it starts.
+type word struct {
+	text string
+	pos  lexer.Position
+}
+
+func (p *parser) parseLine(line string) error {
+	if p.inComment {
+		p.inComment = !strings.Contains(line, commentEnd)
+		p.leading = append(p.leading, line)
+		return nil
+	}
+	words := p.split(line)
+	switch {
+	case len(words) == 0 || isProse(words[0].text):
+		p.block, p.entry = nil, nil
+		p.leading = append(p.leading, line)
+	case strings.HasPrefix(words[0].text, commentStart):
+		p.block, p.entry = nil, nil
+		p.leading = append(p.leading, line)
+		p.inComment = !strings.Contains(line[strings.Index(line, commentStart):], commentEnd)
+	case strings.HasPrefix(words[0].text, "#"):
+		var header []string
+		if rest := words[0].text[1:]; rest != "" {
+			header = append(header, rest)
+		}
+		for _, w := range words[1:] {
+			header = append(header, w.text)
+		}
+		if len(header) == 0 {
+			return fmt.Errorf("%s: empty header", words[0].pos)
+		}
+		p.endGrouping()
+		p.t.Groupings = append(p.t.Groupings, ast.Grouping{Header: header, Source: line})
+		p.block, p.entry = nil, nil
+	case words[0].text == "|" && p.entry != nil:
+		var note ast.NoteLine
+		for _, w := range words[1:] {
+			note.Text = append(note.Text, w.text)
+		}
+		p.entry.Notes = append(p.entry.Notes, note)
+		p.entry.Source = append(p.entry.Source, line)
+	default:
+		if p.block == nil {
+			p.newBlock()
+		}
+		p.entry = parseEntry(words)
+		p.entry.Source = []string{line}
+		p.block.Children = append(p.block.Children, p.entry)
+	}
+	return nil
+}
+
+const (
+	commentStart = "<!--"
+	commentEnd   = "-->"
+)
+
+// isProse reports whether a line starting with word is markdown that is not
+// an entry or header.
+func isProse(word string) bool {
+	return strings.HasPrefix(word, ">") || strings.HasPrefix(word, "##")
+}
+
+// newBlock starts a block in the current grouping, with the lines that were
+// read before it.
+func (p *parser) newBlock() {
+	if len(p.t.Groupings) == 0 {
+		p.t.Groupings = append(p.t.Groupings, ast.Grouping{})
+	}
+	g := &p.t.Groupings[len(p.t.Groupings)-1]
+	g.Blocks = append(g.Blocks, ast.Block{Leading: p.leading})
+	p.block = &g.Blocks[len(g.Blocks)-1]
+	p.leading = nil
+}
+
+// endGrouping keeps the lines read after the last entry of the current
+// grouping in a block of their own.
+func (p *parser) endGrouping() {
+	if len(p.leading) > 0 {
+		p.newBlock()
+	}
+}
+
+// split splits a line into words, keeping track of where each starts.
+func (p *parser) split(line string) []word {
+	var words []word
+	column := 1
+	for i := 0; i < len(line); {
+		if isSpace(line[i]) {
+			i++
+			column++
+			continue
+		}
+		start, startColumn := i, column
+		for i < len(line) && !isSpace(line[i]) {
+			_, size := utf8.DecodeRuneInString(line[i:])
+			i += size
+			column++
+		}
+		pos := p.pos
+		pos.Offset += start
+		pos.Column = startColumn
+		words = append(words, word{text: line[start:i], pos: pos})
+	}
+	return words
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\f'
+}
+
+func parseEntry(words []word) *ast.Entry {
+	e := &ast.Entry{Pos: words[0].pos}
+	i := 0
+	if words[i].text == "x" {
+		e.Completed = true
+		i++
+	}
+	if i < len(words) && isPriority(words[i].text) {
+		priority := words[i].text
+		e.Priority = &priority
+		i++
+	}
+	if i+1 < len(words) && isDate(words[i].text) && isDate(words[i+1].text) {
+		e.CompletionDate = parseDate(words[i].text)
+		e.CreationDate = parseDate(words[i+1].text)
+		i += 2
+	} else if i < len(words) && isDate(words[i].text) {
+		e.CreationDate = parseDate(words[i].text)
+		i++
+	}
+
+	for _, w := range words[i:] {
+		text := w.text
+		switch {
+		case len(text) > 1 && text[0] == '+':
+			project := text[1:]
+			e.Description = append(e.Description, &ast.DescriptionPart{Project: &project})
+		case len(text) > 1 && text[0] == '@':
+			context := text[1:]
+			e.Description = append(e.Description, &ast.DescriptionPart{Context: &context})
+		case isTag(text):
+			key, value, _ := strings.Cut(text, ":")
+			e.Description = append(e.Description, &ast.DescriptionPart{
+				SpecialTag: &ast.SpecialTag{Key: key, Value: value},
+			})
+		default:
+			if n := len(e.Description); n > 0 && len(e.Description[n-1].Text) > 0 {
+				e.Description[n-1].Text = append(e.Description[n-1].Text, text)
+				continue
+			}
+			e.Description = append(e.Description, &ast.DescriptionPart{Text: []string{text}})
+		}
+	}
+	return e
+}
+
+// isPriority reports whether s is a priority like (A).
+func isPriority(s string) bool {
+	return len(s) == 3 && s[0] == '(' && s[1] >= 'A' && s[1] <= 'Z' && s[2] == ')'
+}
+
+// isDate reports whether s is shaped like a YYYY-MM-DD date. It may still not
+// be a real day.
+func isDate(s string) bool {
+	if len(s) != len("2006-01-02") {
+		return false
+	}
+	for i := range s {
+		if i == 4 || i == 7 {
+			if s[i] != '-' {
+				return false
+			}
+		} else if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func parseDate(s string) *dates.Date {
+	d := new(dates.Date)
+	d.UnmarshalText([]byte(s)) // Never fails; invalid dates are kept.
+	return d
+}
+
+// isTag reports whether s is a key:value tag. Like the grammar, the value
+// after the last colon must not be empty. Unlike it, URLs are not tags.
+func isTag(s string) bool {
+	last := strings.LastIndexByte(s, ':')
+	if last <= 0 || last == len(s)-1 {
+		return false
+	}
+	_, value, _ := strings.Cut(s, ":")
+	return !strings.HasPrefix(value, "//")
+}
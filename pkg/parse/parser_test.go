@@ -0,0 +1,305 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// compareDates compares dates by what they were parsed from, since invalid
+// dates keep the error that made them invalid.
+var compareDates = cmp.Comparer(func(a, b dates.Date) bool {
+	return a.String() == b.String() && a.Valid() == b.Valid()
+})
+
+// ignoreSource ignores what Parse keeps that the grammar does not.
+var ignoreSource = cmpopts.IgnoreFields(ast.Entry{}, "Source")
+
+// withoutTrivia drops the lines that are not entries or headers from t, which
+// the grammar does not keep.
+func withoutTrivia(t ast.TodoTxt) ast.TodoTxt {
+	var result ast.TodoTxt
+	for _, g := range t.Groupings {
+		kept := ast.Grouping{Header: g.Header}
+		for _, b := range g.Blocks {
+			if len(b.Children) > 0 {
+				kept.Blocks = append(kept.Blocks, ast.Block{Children: b.Children})
+			}
+		}
+		if len(kept.Header) > 0 || len(kept.Blocks) > 0 {
+			result.Groupings = append(result.Groupings, kept)
+		}
+	}
+	return result
+}
+
+// parseReference parses input with the participle grammar, which Parse is
+// tested against.
+func parseReference(filename string, input []byte) (ast.TodoTxt, error) {
+	var t ast.TodoTxt
+	err := BuildParser().ParseBytes(filename, input, &t)
+	return t, err
+}
+
+func TestParseMatchesGrammar(t *testing.T) {
+	inputs := make(map[string][]byte)
+	files, err := filepath.Glob("../../testdata/*.input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		input, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[filepath.Base(file)] = input
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		inputs[fmt.Sprintf("generated%d", i)] = generateTodoTxt(r, 1+r.Intn(40))
+	}
+	inputs["no trailing newline"] = []byte("# Today\n\n(A) 2022-01-01 call mom +family\n  | about the trip")
+	inputs["leading blank lines"] = []byte("\n\n  \nhello world\n")
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			want, err := parseReference(name, input)
+			if err != nil {
+				t.Skipf("not in the grammar's language: %v", err)
+			}
+			got, err := Parse(name, input)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if diff := cmp.Diff(withoutTrivia(got), want, compareDates, ignoreSource); diff != "" {
+				t.Errorf("Parse differs from the grammar (-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseFidelity(t *testing.T) {
+	table := []struct {
+		name   string
+		input  string
+		want   string // The input if empty.
+		assert func(t *testing.T, result ast.TodoTxt)
+	}{{
+		name: "notes keep every word",
+		input: `# Today
+2022-01-01 write report
+  | email @bob about +proj (A) on 2022-01-02 # ok
+  | 2021-12-21 the raised bed
+`,
+		assert: func(t *testing.T, result ast.TodoTxt) {
+			if got := result.Groupings[0].Len(); got != 1 {
+				t.Errorf("got %d entries, want 1", got)
+			}
+		},
+	}, {
+		name:  "priority and date in the description",
+		input: "# Inbox\n  2022-01-01 move (A) tasks to 2022-02-01\n",
+		assert: func(t *testing.T, result ast.TodoTxt) {
+			if got := result.Groupings[0].Len(); got != 1 {
+				t.Errorf("got %d entries, want 1", got)
+			}
+		},
+	}, {
+		name:  "header with any words",
+		input: "# Project +vogon @home 2022\n\n  foo\n",
+	}, {
+		name:  "header at end of file",
+		input: "foo\n# Logged",
+		want:  "# Inbox\n\nfoo\n",
+	}, {
+		name:  "carriage returns",
+		input: "# Today\r\n\r\nfoo bar\r\n",
+		want:  "# Today\n\nfoo bar\n",
+	}, {
+		name:  "urls are not tags",
+		input: "# Inbox\nread https://example.com/a:b due:2022-01-01\n",
+		assert: func(t *testing.T, result ast.TodoTxt) {
+			e := result.Groupings[0].Blocks[0].Children[0]
+			if _, ok := e.Tag("https"); ok {
+				t.Errorf("url was read as a tag")
+			}
+			if got, _ := e.Tag("due"); got != "2022-01-01" {
+				t.Errorf("got due:%s, want due:2022-01-01", got)
+			}
+		},
+	}, {
+		name:  "note after a blank line",
+		input: "# Inbox\nfoo\n\n| bar\n",
+		assert: func(t *testing.T, result ast.TodoTxt) {
+			if got := result.Groupings[0].Len(); got != 2 {
+				t.Errorf("got %d entries, want 2", got)
+			}
+		},
+	}, {
+		name:  "spacing",
+		input: "#Today\n\n\n\tcall   mom\t+family\n      |  about\tthe   trip\n\n\n\n# Next\n  fix bike\n",
+	}, {
+		name: "prose and comments",
+		input: `<!-- vim: set tw=0: -->
+
+# Next
+
+> Things to do once the move is done.
+> Nothing here is urgent.
+
+  2022-01-01 paint the fence
+## Garden
+  2022-01-01 plant tomatoes
+<!--
+  2022-01-01 this was never a good idea
+-->
+  2022-01-01 mulch the beds
+`,
+		assert: func(t *testing.T, result ast.TodoTxt) {
+			var got []string
+			for _, g := range result.Groupings {
+				for _, b := range g.Blocks {
+					for _, e := range b.Children {
+						got = append(got, e.DescriptionText())
+					}
+				}
+			}
+			want := []string{"paint the fence", "plant tomatoes", "mulch the beds"}
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("unexpected entries (-got,+want):\n%s", diff)
+			}
+		},
+	}, {
+		name:  "changed entries are rewritten",
+		input: "# Today\n\n  call   mom\n     | soon\n  fix  bike\n",
+		want:  "# Today\n\n  call   mom\n     | soon\n  2022-01-01 fix bike\n",
+		assert: func(t *testing.T, result ast.TodoTxt) {
+			result.Groupings[0].Blocks[0].Children[1].CreationDate = parseDate("2022-01-01")
+		},
+	}, {
+		name:  "changed headers are rewritten",
+		input: "#  Next   week\n  foo\n",
+		want:  "# Later\n\n  foo\n",
+		assert: func(t *testing.T, result ast.TodoTxt) {
+			result.Groupings[0].Header = []string{"Later"}
+		},
+	}}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Parse("testinput", []byte(tc.input))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if tc.assert != nil {
+				tc.assert(t, result)
+			}
+			var got bytes.Buffer
+			if err := result.DumpText(&got); err != nil {
+				t.Fatal(err)
+			}
+			want := tc.want
+			if want == "" {
+				want = tc.input
+			}
+			if diff := cmp.Diff(got.String(), want); diff != "" {
+				t.Errorf("unexpected output (-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"#\nfoo\n", "foo\n  #  \n"} {
+		if _, err := Parse("testinput", []byte(input)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an empty header error", input)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	input := generateTodoTxt(rand.New(rand.NewSource(1)), 10000)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse("", input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParticiple(b *testing.B) {
+	input := generateTodoTxt(rand.New(rand.NewSource(1)), 10000)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseReference("", input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// generateTodoTxt writes a file like a long lived logbook, with only the
+// kinds of lines that the grammar and Parse agree on.
+func generateTodoTxt(r *rand.Rand, entries int) []byte {
+	headers := []string{"Today", "Next", "Evening", "Waiting", "Someday", "Logged", "Scheduled"}
+	words := []string{
+		"call", "mom", "fix", "the", "bike", "write", "report", "for", "Q3",
+		"buy", "milk", "x-ray", "review", "PR", "3:30pm", "+home",
+		"+work", "@phone", "@errands", "due:2022-03-04", "sched:tomorrow",
+		"id:a1b2", "dep:a1b2,c3d4", "rec:1w", "age:12", "move:someday",
+	}
+	noteWords := []string{"see", "the", "thread", "in", "email", "step", "1.", "x", "(ask)", "first", "http://example.com"}
+	date := func() string {
+		return dates.NewDate(2020+r.Intn(4), 1, 1+r.Intn(365)).String()
+	}
+
+	var b strings.Builder
+	if r.Intn(2) == 0 {
+		fmt.Fprintf(&b, "# %s\n", headers[r.Intn(len(headers))])
+	}
+	for i := 0; i < entries; i++ {
+		switch n := r.Intn(20); {
+		case n == 0:
+			fmt.Fprintf(&b, "\n#%s\n\n", headers[r.Intn(len(headers))])
+		case n < 3:
+			b.WriteString("\n")
+		}
+
+		var line []string
+		if r.Intn(3) == 0 {
+			line = append(line, "x")
+		}
+		if r.Intn(4) == 0 {
+			line = append(line, fmt.Sprintf("(%c)", 'A'+r.Intn(26)))
+		}
+		switch r.Intn(3) {
+		case 0:
+			line = append(line, date(), date())
+		case 1:
+			line = append(line, date())
+		}
+		for j := 1 + r.Intn(8); j > 0; j-- {
+			line = append(line, words[r.Intn(len(words))])
+		}
+		fmt.Fprintf(&b, "%s%s\n", strings.Repeat(" ", r.Intn(3)), strings.Join(line, " "))
+
+		for j := r.Intn(6) - 3; j > 0; j-- {
+			var note []string
+			for k := r.Intn(6); k > 0; k-- {
+				note = append(note, noteWords[r.Intn(len(noteWords))])
+			}
+			fmt.Fprintf(&b, "    | %s\n", strings.Join(note, " "))
+		}
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// runRender writes the formatted file as a page to read rather than edit.
+func runRender(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "-", "todo.txt file path to render")
+	format := fs.String("format", "html", "Output format, only html for now")
+	output := fs.String("o", "-", "File to write to")
+	title := fs.String("title", "", "Page title (default the file name)")
+	fs.Parse(args)
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	t, err := loadTodoTxt(*filename, now)
+	if err != nil {
+		return err
+	}
+	if *title == "" {
+		*title = filepath.Base(*filename)
+		if *filename == "-" {
+			*title = "todo.txt"
+		}
+	}
+
+	var buf bytes.Buffer
+	switch *format {
+	case "html":
+		err = renderHTML(&buf, t, now, *title)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	if *output == "-" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return writeFileAtomic(*output, buf.Bytes())
+}
+
+// htmlSection is a header and the entries under it, split into groups like
+// the weeks of the Logbook.
+type htmlSection struct {
+	Header string
+	Class  string
+	Count  int
+	Groups []htmlGroup
+}
+
+type htmlGroup struct {
+	Title   string
+	Entries []htmlEntry
+}
+
+type htmlEntry struct {
+	Completed  bool
+	Priority   string
+	Dates      string
+	Parts      []htmlPart
+	Badge      string
+	BadgeClass string
+	Notes      [][]htmlPart // Paragraphs.
+}
+
+// htmlPart is a word or run of words, styled by Class like the syntax group
+// vim would highlight it with, or linked to Href.
+type htmlPart struct {
+	Text  string
+	Class string
+	Href  string
+}
+
+// headerClasses are the CSS classes of the headers syntax/todotxt.vim colors.
+var headerClasses = map[string]string{
+	"Today":     "today",
+	"Evening":   "eve",
+	"Inbox":     "inbox",
+	"Next":      "next",
+	"Scheduled": "sched",
+	"Logged":    "log",
+}
+
+func renderHTML(out io.Writer, t ast.TodoTxt, now time.Time, title string) error {
+	today := dates.DateOf(now)
+	var sections []htmlSection
+	for i, g := range t.Groupings {
+		if g.Len() == 0 {
+			continue
+		}
+		header := strings.Join(g.Header, " ")
+		if header == "" && i == 0 {
+			header = "Inbox"
+		}
+		section := htmlSection{Header: header, Class: headerClasses[header], Count: g.Len()}
+		for _, b := range g.Blocks {
+			// Blocks stay apart as in the file, except in the Logbook,
+			// which is grouped by the week entries were completed in.
+			newBlock := true
+			for _, e := range b.Children {
+				if e == nil {
+					continue
+				}
+				groupTitle := ""
+				if header == "Logged" && e.CompletionDate != nil && e.CompletionDate.Valid() {
+					groupTitle = "Week of " + e.CompletionDate.StartOfWeek().String()
+					newBlock = false
+				}
+				if n := len(section.Groups); newBlock || n == 0 || section.Groups[n-1].Title != groupTitle {
+					section.Groups = append(section.Groups, htmlGroup{Title: groupTitle})
+					newBlock = false
+				}
+				group := &section.Groups[len(section.Groups)-1]
+				group.Entries = append(group.Entries, newHTMLEntry(e, now, today))
+			}
+		}
+		sections = append(sections, section)
+	}
+
+	return htmlTemplate.Execute(out, map[string]any{
+		"Title":    title,
+		"Today":    today.String(),
+		"Sections": sections,
+	})
+}
+
+func newHTMLEntry(e *ast.Entry, now time.Time, today dates.Date) htmlEntry {
+	result := htmlEntry{Completed: e.Completed}
+	if e.Priority != nil {
+		result.Priority = *e.Priority
+	}
+	var ds []string
+	for _, d := range []*dates.Date{e.CompletionDate, e.CreationDate} {
+		if d != nil {
+			ds = append(ds, d.String())
+		}
+	}
+	result.Dates = strings.Join(ds, " ")
+
+	for _, dp := range e.Description {
+		switch {
+		case dp.Project != nil:
+			result.Parts = append(result.Parts, htmlPart{Text: "+" + *dp.Project, Class: "project"})
+		case dp.Context != nil:
+			result.Parts = append(result.Parts, htmlPart{Text: "@" + *dp.Context, Class: "context"})
+		case dp.SpecialTag != nil:
+			result.Parts = append(result.Parts, htmlPart{Text: dp.SpecialTag.Key + ":" + dp.SpecialTag.Value, Class: "tag"})
+			if dp.SpecialTag.Key == "due" && !e.Completed {
+				result.Badge, result.BadgeClass = dueBadge(dp.SpecialTag, now, today)
+			}
+		default:
+			result.Parts = append(result.Parts, linkWords(dp.Text)...)
+		}
+	}
+
+	var paragraph []htmlPart
+	for _, line := range e.Notes {
+		if len(line.Text) == 0 {
+			if len(paragraph) > 0 {
+				result.Notes = append(result.Notes, paragraph)
+			}
+			paragraph = nil
+			continue
+		}
+		paragraph = append(paragraph, linkWords(line.Text)...)
+	}
+	if len(paragraph) > 0 {
+		result.Notes = append(result.Notes, paragraph)
+	}
+	return result
+}
+
+// dueBadge describes when an entry is due, relative to today.
+func dueBadge(tag *ast.SpecialTag, now time.Time, today dates.Date) (text, class string) {
+	due, err := tag.Date(now)
+	if err != nil {
+		return "", ""
+	}
+	switch days := today.DaysUntil(due); {
+	case days < 0:
+		return fmt.Sprintf("overdue %dd", -days), "overdue"
+	case days == 0:
+		return "due today", "due-today"
+	default:
+		return "due " + due.Weekday().String()[:3] + " " + due.String(), "due"
+	}
+}
+
+// linkWords splits text into plain runs of words and links.
+func linkWords(words []string) []htmlPart {
+	var parts []htmlPart
+	for _, word := range words {
+		if strings.HasPrefix(word, "http://") || strings.HasPrefix(word, "https://") {
+			parts = append(parts, htmlPart{Text: word, Href: word})
+			continue
+		}
+		if n := len(parts); n > 0 && parts[n-1].Href == "" && parts[n-1].Class == "" {
+			parts[n-1].Text += " " + word
+			continue
+		}
+		parts = append(parts, htmlPart{Text: word})
+	}
+	return parts
+}
+
+// htmlTemplate is a self contained page. The colors follow
+// syntax/todotxt.vim.
+var htmlTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: ui-monospace, Menlo, Consolas, monospace; max-width: 60em; margin: 2em auto; padding: 0 1em; }
+summary { font-weight: bold; cursor: pointer; padding: 0.2em 0.5em; color: white; background: black; }
+details.today > summary { color: black; background: #c4a000; }
+details.eve > summary { color: black; background: #a040a0; }
+details.inbox > summary { color: black; background: #00a0a0; }
+details.next > summary { color: black; background: #00a000; }
+details.sched > summary { color: black; background: #c00000; }
+details.log > summary { color: black; background: #3060c0; }
+h3 { font-size: 1em; margin: 0.8em 0 0.2em; }
+ul { list-style: none; padding-left: 1em; }
+li { margin: 0.2em 0; }
+li.completed > .line { color: #666; }
+.complete { font-weight: bold; }
+.date, .tag, .notes { color: #0000c0; }
+.project { color: #af5f00; font-weight: bold; }
+.context { color: #008000; }
+.badge { font-size: 0.8em; padding: 0 0.4em; border-radius: 0.3em; margin-left: 0.5em; background: #ddd; }
+.badge.due-today { background: #c4a000; }
+.badge.overdue { background: #c00000; color: white; }
+.notes p { margin: 0.2em 0 0.2em 2em; }
+footer { color: #666; margin-top: 2em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Sections}}<details open{{with .Class}} class="{{.}}"{{end}}>
+<summary>{{.Header}} ({{.Count}})</summary>
+{{range .Groups}}{{with .Title}}<h3>{{.}}</h3>
+{{end}}<ul>
+{{range .Entries}}<li{{if .Completed}} class="completed"{{end}}><span class="line">{{if .Completed}}<span class="complete">x</span> {{end}}{{with .Priority}}<span class="priority">{{.}}</span> {{end}}{{with .Dates}}<span class="date">{{.}}</span> {{end}}{{range .Parts}}{{template "part" .}} {{end}}</span>{{if .Badge}}<span class="badge {{.BadgeClass}}">{{.Badge}}</span>{{end}}{{if .Notes}}
+<div class="notes">{{range .Notes}}<p>{{range .}}{{template "part" .}} {{end}}</p>{{end}}</div>{{end}}</li>
+{{end}}</ul>
+{{end}}</details>
+{{end}}<footer>Rendered for {{.Today}}.</footer>
+</body>
+</html>
+{{define "part"}}{{if .Href}}<a href="{{.Href}}">{{.Text}}</a>{{else if .Class}}<span class="{{.Class}}">{{.Text}}</span>{{else}}{{.Text}}{{end}}{{end}}`))
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderHTML(t *testing.T) {
+	input := strings.Join([]string{
+		"# Inbox",
+		"",
+		"  2022-01-01 call mom +family @phone due:2021-12-30",
+		"           | see https://example.com/x for <details>",
+		"           |",
+		"           | second paragraph",
+		"  2022-01-01 pay rent due:2022-01-05",
+		"",
+		"# Logged",
+		"",
+		"x 2022-01-01 2021-12-01 this week",
+		"x 2021-12-20 2021-12-01 last week",
+	}, "\n")
+	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
+	todo, err := parseTodoTxt([]byte(input), now)
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := renderHTML(&buf, todo, now, "Status"); err != nil {
+		t.Fatalf("renderHTML failed: %v", err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		"<title>Status</title>",
+		`<details open class="today">`,
+		`<span class="project">&#43;family</span>`,
+		`<span class="context">@phone</span>`,
+		`<span class="badge overdue">overdue 2d</span>`,
+		`<span class="badge due">due Wed 2022-01-05</span>`,
+		`<p>see <a href="https://example.com/x">https://example.com/x</a> for &lt;details&gt; </p><p>second paragraph </p>`,
+		"<h3>Week of 2021-12-27</h3>",
+		"<h3>Week of 2021-12-20</h3>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
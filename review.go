@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// activeHeaders are the headers that keep a project from being stalled.
+var activeHeaders = map[string]bool{
+	"Today":     true,
+	"Next":      true,
+	"Scheduled": true,
+}
+
+type projectReview struct {
+	Project       string `json:"project"`
+	Open          int    `json:"open"`
+	LastCompleted string `json:"last_completed,omitempty"`
+
+	active        bool
+	completedWeek int
+}
+
+type reviewReport struct {
+	// Stalled projects have open tasks, but none in Today, Next, or
+	// Scheduled.
+	Stalled []projectReview `json:"stalled"`
+	// Idle projects have open tasks, but nothing logged recently.
+	Idle []projectReview `json:"idle"`
+	// Snoozed are the tasks that have been put off too many times.
+	Snoozed []snoozedReview `json:"snoozed"`
+}
+
+func runReview(args []string) error {
+	fs := flag.NewFlagSet("review", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "-", "todo.txt file path to review")
+	weeks := fs.Int("weeks", 4, "Report projects with no completions in this many weeks")
+	format := fs.String("format", "text", "Output format, text or json")
+	remind := fs.Bool("remind", false, "Add a reminder to review stalled projects to the Inbox")
+	snoozes := fs.Int("snoozes", 3, "Report tasks snoozed more than this many times")
+	fs.Parse(args)
+
+	if *remind && *filename == "-" {
+		return fmt.Errorf("-remind needs a file to write to")
+	}
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	files, err := loadTodoFiles(*filename)
+	if err != nil {
+		return err
+	}
+	compileFiles(files, now)
+	merged := mergeFiles(files)
+	report := reviewProjects(merged, now, *weeks)
+	report.Snoozed = reviewSnoozed(merged, *snoozes)
+
+	switch *format {
+	case "text":
+		err = report.DumpText(os.Stdout, *weeks, *snoozes)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *remind && len(report.Stalled) > 0 {
+		reminder := report.Reminder(now)
+		if hasOpenEntry(mergeFiles(files), reminder) {
+			return nil // Already reminded.
+		}
+		addToInbox(&files[0].Tree, reminder)
+		return files[0].write()
+	}
+	return nil
+}
+
+// reviewProjects finds projects that are stalled or idle. A project is idle
+// when it has not had a completion in the last weeks weeks.
+func reviewProjects(t ast.TodoTxt, now time.Time, weeks int) reviewReport {
+	projects := make(map[string]*projectReview)
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		for _, name := range e.Projects() {
+			p, ok := projects[name]
+			if !ok {
+				p = &projectReview{Project: name}
+				projects[name] = p
+			}
+			if !e.Completed {
+				p.Open++
+				p.active = p.active || activeHeaders[heading]
+				continue
+			}
+			if week := e.CompletedWeek(); week > p.completedWeek {
+				p.completedWeek = week
+				p.LastCompleted = e.CompletionDate.String()
+			}
+		}
+		return nil
+	})
+
+	cutoffYear, cutoffWeek := dates.DateOf(now).AddDays(-7 * weeks).ISOWeek()
+	cutoff := cutoffYear*100 + cutoffWeek
+
+	report := reviewReport{
+		Stalled: []projectReview{},
+		Idle:    []projectReview{},
+	}
+	for _, p := range projects {
+		if p.Open == 0 {
+			continue // Nothing left to do.
+		}
+		if !p.active {
+			report.Stalled = append(report.Stalled, *p)
+		}
+		if p.completedWeek < cutoff {
+			report.Idle = append(report.Idle, *p)
+		}
+	}
+	byName := func(l []projectReview) func(i, j int) bool {
+		return func(i, j int) bool { return l[i].Project < l[j].Project }
+	}
+	sort.Slice(report.Stalled, byName(report.Stalled))
+	sort.Slice(report.Idle, byName(report.Idle))
+	return report
+}
+
+func (r reviewReport) DumpText(out io.Writer, weeks, snoozes int) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, "Stalled projects (nothing in Today, Next, or Scheduled):")
+	for _, p := range r.Stalled {
+		fmt.Fprintf(w, "  +%s (%d open)\n", p.Project, p.Open)
+	}
+	fmt.Fprintf(w, "\nIdle projects (nothing logged in %d weeks):\n", weeks)
+	for _, p := range r.Idle {
+		last := "never completed"
+		if p.LastCompleted != "" {
+			last = "last completed " + p.LastCompleted
+		}
+		fmt.Fprintf(w, "  +%s (%d open, %s)\n", p.Project, p.Open, last)
+	}
+	fmt.Fprintf(w, "\nSnoozed tasks (put off more than %d times):\n", snoozes)
+	for _, s := range r.Snoozed {
+		fmt.Fprintf(w, "  %s (%d times, in %s)\n", s.Entry, s.Snoozed, s.Header)
+	}
+	return w.Flush()
+}
+
+// Reminder is an Inbox entry asking to review the stalled projects.
+func (r reviewReport) Reminder(now time.Time) *ast.Entry {
+	today := dates.DateOf(now)
+	e := &ast.Entry{
+		CreationDate: &today,
+		Description: []*ast.DescriptionPart{{
+			Text: []string{"review", "stalled", "projects"},
+		}},
+	}
+	for i := range r.Stalled {
+		e.Description = append(e.Description, &ast.DescriptionPart{
+			Project: &r.Stalled[i].Project,
+		})
+	}
+	return e
+}
+
+// hasOpenEntry reports whether t has an open entry with the same words as e,
+// ignoring its dates.
+func hasOpenEntry(t ast.TodoTxt, e *ast.Entry) bool {
+	want := descriptionText(e)
+	found := false
+	visitAllEntries(&t, func(heading string, other *ast.Entry) error {
+		found = found || (!other.Completed && descriptionText(other) == want)
+		return nil
+	})
+	return found
+}
+
+func descriptionText(e *ast.Entry) string {
+	var text strings.Builder
+	(&ast.Entry{Description: e.Description}).DumpText(&text)
+	return text.String()
+}
+
+// addToInbox puts an entry at the top of an already compiled document's
+// Inbox, creating the Inbox if needed.
+func addToInbox(t *ast.TodoTxt, e *ast.Entry) {
+	if len(t.Groupings) == 0 || strings.Join(t.Groupings[0].Header, " ") != "Inbox" {
+		t.Groupings = slices.Insert(t.Groupings, 0, ast.Grouping{
+			Header: []string{"Inbox"},
+		})
+	}
+	inbox := &t.Groupings[0]
+	if len(inbox.Blocks) == 0 {
+		inbox.Blocks = append(inbox.Blocks, ast.Block{})
+	}
+	inbox.Blocks[0].Children = slices.Insert(inbox.Blocks[0].Children, 0, e)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReviewProjects(t *testing.T) {
+	input := strings.Join([]string{
+		"# Next",
+		"",
+		"  2022-01-01 call the bank +money",
+		"",
+		"# Someday",
+		"",
+		"  2022-01-01 paint the shed +house",
+		"  2022-01-01 plant tomatoes +garden",
+		"",
+		"# Scheduled",
+		"",
+		"  2022-01-01 buy a rake +garden sched:2022-03-01",
+		"",
+		"# Logged",
+		"",
+		"x 2022-02-20 2022-01-01 fix the roof +house",
+		"x 2022-01-05 2022-01-01 pay rent +money",
+		"x 2022-01-05 2022-01-01 finish the novel +book",
+	}, "\n")
+
+	now := time.Date(2022, time.February, 25, 0, 0, 0, 0, time.UTC)
+	todo, err := parseTodoTxt([]byte(input), now)
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+
+	got := reviewProjects(todo, now, 4)
+	want := reviewReport{
+		Stalled: []projectReview{
+			{Project: "house", Open: 1, LastCompleted: "2022-02-20"},
+		},
+		Idle: []projectReview{
+			{Project: "garden", Open: 2},
+			{Project: "money", Open: 1, LastCompleted: "2022-01-05"},
+		},
+	}
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreUnexported(projectReview{})); diff != "" {
+		t.Errorf("reviewProjects returned unexpected result (-got,+want):\n%s", diff)
+	}
+}
+
+func TestReviewRemindOnce(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.txt")
+	input := "# Someday\n\n  2022-01-01 paint the shed +house\n"
+	if err := os.WriteFile(filename, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := runReview([]string{"-f", filename, "-remind"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(got), "review stalled projects +house"); n != 1 {
+		t.Errorf("found %d reminders, want 1:\n%s", n, got)
+	}
+}
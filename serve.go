@@ -0,0 +1,455 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+// runServe serves a JSON API for reading and changing a todo file and the
+// files it includes. Entries are known by their position in the formatted
+// files, so changes to an entry must send the ETag they read in an If-Match
+// header. A change to files that have been written since, or that format
+// differently since the day changed, fails with 412 Precondition Failed.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "todo.txt", "todo.txt file path to serve")
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+	fs.Parse(args)
+
+	if *filename == "-" {
+		return fmt.Errorf("cannot serve stdin")
+	}
+	if _, err := clock.Now(); err != nil {
+		return err
+	}
+	log.Printf("serving %s on http://%s", *filename, *addr)
+	return http.ListenAndServe(*addr, newServer(*filename, clock))
+}
+
+// apiEntry is an entry as the API reads and writes it.
+type apiEntry struct {
+	ID             int               `json:"id"`
+	Header         string            `json:"header"`
+	Text           string            `json:"text"`
+	Completed      bool              `json:"completed"`
+	Priority       string            `json:"priority,omitempty"`
+	CreationDate   string            `json:"creation_date,omitempty"`
+	CompletionDate string            `json:"completion_date,omitempty"`
+	Description    string            `json:"description"`
+	Projects       []string          `json:"projects,omitempty"`
+	Contexts       []string          `json:"contexts,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	Notes          []string          `json:"notes,omitempty"`
+}
+
+// apiRequest is the body of a request that adds, edits, or moves an entry.
+type apiRequest struct {
+	Text   string `json:"text"`
+	Header string `json:"header"`
+}
+
+// statusError is an error with the HTTP status to report it with.
+type statusError struct {
+	code int
+	err  error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+
+func httpError(code int, format string, args ...any) error {
+	return &statusError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+type server struct {
+	filename string
+	clock    *clockFlags
+
+	mu sync.Mutex // Held while reading and writing the file.
+}
+
+func newServer(filename string, clock *clockFlags) http.Handler {
+	s := &server{filename: filename, clock: clock}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/entries", s.handleEntries)
+	mux.HandleFunc("/entries/", s.handleEntry)
+	mux.HandleFunc("/stats", s.handleStats)
+	return mux
+}
+
+// handleEntries lists entries on GET and adds one on POST.
+func (s *server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.respond(w, func() (any, string, error) {
+			l, err := s.load()
+			if err != nil {
+				return nil, "", err
+			}
+			entries, err := filterEntries(l.tree, r)
+			return entries, l.etag, err
+		})
+	case http.MethodPost:
+		// Adding an entry does not depend on where the others are.
+		s.update(w, r, false, func(l *loaded, req apiRequest) (*ast.Entry, error) {
+			e, err := parseEntry(req.Text)
+			if err != nil {
+				return nil, httpError(http.StatusBadRequest, "%v", err)
+			}
+			moveEntry(&l.files[0].Tree, e, req.Header)
+			return e, nil
+		})
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, httpError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+	}
+}
+
+// handleEntry serves /entries/ID, which reads an entry on GET and replaces
+// its text on PUT, and /entries/ID/complete and /entries/ID/move.
+func (s *server) handleEntry(w http.ResponseWriter, r *http.Request) {
+	idText, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/entries/"), "/")
+	id, err := strconv.Atoi(idText)
+	if err != nil {
+		writeError(w, httpError(http.StatusNotFound, "no entry %q", idText))
+		return
+	}
+	find := func(t ast.TodoTxt) (*ast.Entry, error) {
+		entries := allEntries(t)
+		if id < 1 || id > len(entries) {
+			return nil, httpError(http.StatusNotFound, "no entry %d", id)
+		}
+		return entries[id-1].Entry, nil
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		s.respond(w, func() (any, string, error) {
+			l, err := s.load()
+			if err != nil {
+				return nil, "", err
+			}
+			if _, err := find(l.tree); err != nil {
+				return nil, "", err
+			}
+			return allEntries(l.tree)[id-1], l.etag, nil
+		})
+	case action == "" && r.Method == http.MethodPut:
+		s.update(w, r, true, func(l *loaded, req apiRequest) (*ast.Entry, error) {
+			e, err := find(l.tree)
+			if err != nil {
+				return nil, err
+			}
+			edited, err := parseEntry(req.Text)
+			if err != nil {
+				return nil, httpError(http.StatusBadRequest, "%v", err)
+			}
+			if edited.CreationDate == nil {
+				edited.CreationDate = e.CreationDate
+			}
+			*e = *edited
+			return e, nil
+		})
+	case action == "complete" && r.Method == http.MethodPost:
+		s.update(w, r, true, func(l *loaded, req apiRequest) (*ast.Entry, error) {
+			e, err := find(l.tree)
+			if err != nil {
+				return nil, err
+			}
+			e.Completed = true
+			return e, nil
+		})
+	case action == "move" && r.Method == http.MethodPost:
+		s.update(w, r, true, func(l *loaded, req apiRequest) (*ast.Entry, error) {
+			e, err := find(l.tree)
+			if err != nil {
+				return nil, err
+			}
+			if req.Header == "" {
+				return nil, httpError(http.StatusBadRequest, "missing header to move to")
+			}
+			// The entry moves within the file it is in.
+			for _, f := range l.files {
+				if len(findEntries(&f.Tree, func(_ string, other *ast.Entry) bool { return other == e })) > 0 {
+					moveEntry(&f.Tree, e, req.Header)
+				}
+			}
+			return e, nil
+		})
+	case action != "" && action != "complete" && action != "move":
+		writeError(w, httpError(http.StatusNotFound, "no such action %q", action))
+	default:
+		writeError(w, httpError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+	}
+}
+
+// handleStats reports the same statistics as vogon stats.
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
+	s.respond(w, func() (any, string, error) {
+		weeks := 12
+		if value := r.URL.Query().Get("weeks"); value != "" {
+			var err error
+			if weeks, err = strconv.Atoi(value); err != nil {
+				return nil, "", httpError(http.StatusBadRequest, "bad weeks: %v", err)
+			}
+		}
+		l, err := s.load()
+		if err != nil {
+			return nil, "", err
+		}
+		return computeStats(l.tree, l.now, weeks), l.etag, nil
+	})
+}
+
+// loaded is the served file and the files it includes, formatted.
+type loaded struct {
+	files []*todoFile
+	// tree is the files merged, which is what entries are numbered in.
+	tree ast.TodoTxt
+	etag string
+	// now is the time the files were formatted for.
+	now time.Time
+}
+
+// load reads and formats the file and the files it includes.
+func (s *server) load() (*loaded, error) {
+	now, err := s.clock.Now()
+	if err != nil {
+		return nil, err
+	}
+	files, err := loadTodoFiles(s.filename)
+	if err != nil {
+		return nil, err
+	}
+	compileFiles(files, now)
+	l := &loaded{files: files, now: now}
+	return l, l.refresh()
+}
+
+// refresh merges the files again after they changed, and takes their ETag.
+func (l *loaded) refresh() error {
+	l.tree = mergeFiles(l.files)
+	tag, err := etag(l.files)
+	l.etag = tag
+	return err
+}
+
+func (s *server) respond(w http.ResponseWriter, read func() (any, string, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, tag, err := read()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", tag)
+	writeJSON(w, http.StatusOK, v)
+}
+
+// update applies a change to the files and writes them back, then responds
+// with the entry that changed. Changes to an entry by its ID must send an
+// If-Match header, so that they cannot change some other entry.
+func (s *server) update(w http.ResponseWriter, r *http.Request, byID bool, change func(l *loaded, req apiRequest) (*ast.Entry, error)) {
+	var req apiRequest
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, httpError(http.StatusBadRequest, "bad request body: %v", err))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.filename)
+	if errors.Is(err, errLocked) {
+		err = httpError(http.StatusConflict, "%s is being written, try again", s.filename)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer unlock()
+
+	l, err := s.load()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	match := r.Header.Get("If-Match")
+	if byID && match == "" {
+		w.Header().Set("ETag", l.etag)
+		writeError(w, httpError(http.StatusPreconditionRequired, "send the ETag the entry was read with in If-Match"))
+		return
+	}
+	if match != "" && match != "*" && match != l.etag {
+		w.Header().Set("ETag", l.etag)
+		writeError(w, httpError(http.StatusPreconditionFailed, "%s has changed", s.filename))
+		return
+	}
+
+	e, err := change(l, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	compileFiles(l.files, l.now)
+	if err := writeFiles(l.files); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := l.refresh(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", l.etag)
+	for _, entry := range allEntries(l.tree) {
+		if entry.Entry == e {
+			code := http.StatusOK
+			if r.Method == http.MethodPost && r.URL.Path == "/entries" {
+				code = http.StatusCreated
+			}
+			writeJSON(w, code, entry)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// headerEntry is an entry along with where it is in the formatted file.
+type headerEntry struct {
+	ID     int
+	Header string
+	Entry  *ast.Entry
+}
+
+func (h headerEntry) MarshalJSON() ([]byte, error) {
+	e := h.Entry
+	result := apiEntry{
+		ID:          h.ID,
+		Header:      h.Header,
+		Text:        strings.TrimSpace(entryText(e)),
+		Completed:   e.Completed,
+		Description: e.DescriptionText(),
+		Projects:    e.Projects(),
+		Contexts:    e.Contexts(),
+	}
+	if e.Priority != nil {
+		result.Priority = *e.Priority
+	}
+	if e.CreationDate != nil {
+		result.CreationDate = e.CreationDate.String()
+	}
+	if e.CompletionDate != nil {
+		result.CompletionDate = e.CompletionDate.String()
+	}
+	for _, dp := range e.Description {
+		if dp.SpecialTag == nil {
+			continue
+		}
+		if result.Tags == nil {
+			result.Tags = make(map[string]string)
+		}
+		result.Tags[dp.SpecialTag.Key] = dp.SpecialTag.Value
+	}
+	for _, line := range e.Notes {
+		result.Notes = append(result.Notes, strings.Join(line.Text, " "))
+	}
+	return json.Marshal(result)
+}
+
+// entryText returns the entry as it is written in the file, without notes.
+func entryText(e *ast.Entry) string {
+	withoutNotes := *e
+	withoutNotes.Notes, withoutNotes.Source = nil, nil
+	var b strings.Builder
+	withoutNotes.DumpText(&b)
+	return b.String()
+}
+
+// allEntries lists the entries of t in order. Their IDs count up from 1.
+func allEntries(t ast.TodoTxt) []headerEntry {
+	var entries []headerEntry
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		entries = append(entries, headerEntry{ID: len(entries) + 1, Header: heading, Entry: e})
+		return nil
+	})
+	return entries
+}
+
+// filterEntries lists the entries matching the query parameters of r: header,
+// project, context, tag (as key or key:value), completed (true or false), and
+// q, which is text to look for in the description.
+func filterEntries(t ast.TodoTxt, r *http.Request) ([]headerEntry, error) {
+	query := r.URL.Query()
+	var completed *bool
+	if value := query.Get("completed"); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, httpError(http.StatusBadRequest, "bad completed: %v", err)
+		}
+		completed = &b
+	}
+	tagKey, tagValue, hasTagValue := strings.Cut(query.Get("tag"), ":")
+	search := strings.ToLower(query.Get("q"))
+
+	entries := []headerEntry{}
+	for _, h := range allEntries(t) {
+		e := h.Entry
+		value, hasTag := e.Tag(tagKey)
+		switch {
+		case query.Has("header") && !strings.EqualFold(h.Header, query.Get("header")):
+		case query.Has("project") && !slices.Contains(e.Projects(), query.Get("project")):
+		case query.Has("context") && !slices.Contains(e.Contexts(), query.Get("context")):
+		case tagKey != "" && (!hasTag || (hasTagValue && value != tagValue)):
+		case completed != nil && e.Completed != *completed:
+		case search != "" && !strings.Contains(strings.ToLower(e.DescriptionText()), search):
+		default:
+			entries = append(entries, h)
+		}
+	}
+	return entries, nil
+}
+
+// etag identifies the files as they are formatted, which is what entry IDs
+// count in. Formatting the same files on another day can move their entries,
+// and changes the ETag.
+func etag(files []*todoFile) (string, error) {
+	h := sha256.New()
+	for _, f := range files {
+		fmt.Fprintf(h, "%s\n", f.Name)
+		if err := f.DumpText(h); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum(nil)), nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		code = statusErr.code
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServe(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.txt")
+	input := strings.Join([]string{
+		"# Inbox",
+		"",
+		"  2022-01-01 call mom +family",
+		"  2022-01-01 fix the sink +home @house",
+	}, "\n") + "\n"
+	if err := os.WriteFile(filename, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newServer(filename, &clockFlags{now: "2022-01-02", tz: "UTC"}))
+	defer srv.Close()
+
+	do := func(method, path, etag, body string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		got, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, string(got)
+	}
+	descriptions := func(body string) []string {
+		t.Helper()
+		var entries []apiEntry
+		if err := json.Unmarshal([]byte(body), &entries); err != nil {
+			t.Fatalf("bad response %q: %v", body, err)
+		}
+		var result []string
+		for _, e := range entries {
+			result = append(result, e.Header+": "+e.Text)
+		}
+		return result
+	}
+
+	resp, body := do("GET", "/entries?project=home", "", "")
+	if diff := cmp.Diff(descriptions(body), []string{"Inbox: 2022-01-01 fix the sink +home @house"}); diff != "" {
+		t.Errorf("unexpected filtered entries (-got,+want):\n%s", diff)
+	}
+	etag := resp.Header.Get("ETag")
+
+	resp, body = do("POST", "/entries", etag, `{"text": "buy milk due:2022-01-02", "header": "Next"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("add failed with %s: %s", resp.Status, body)
+	}
+	if resp, _ = do("POST", "/entries/1/complete", etag, ""); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("want stale ETag to fail, got %s", resp.Status)
+	}
+
+	etag = resp.Header.Get("ETag")
+	if resp, body = do("POST", "/entries/1/complete", etag, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("complete failed with %s: %s", resp.Status, body)
+	}
+	etag = resp.Header.Get("ETag")
+	if resp, body = do("POST", "/entries/1/move", etag, `{"header": "someday"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("move failed with %s: %s", resp.Status, body)
+	}
+	if resp, _ = do("PUT", "/entries/1", "", `{"text": "buy oat milk due:2022-01-02"}`); resp.StatusCode != http.StatusPreconditionRequired {
+		t.Errorf("want a change without If-Match to fail, got %s", resp.Status)
+	}
+	etag = resp.Header.Get("ETag")
+	if resp, body = do("PUT", "/entries/1", etag, `{"text": "buy oat milk due:2022-01-02"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("edit failed with %s: %s", resp.Status, body)
+	}
+	if resp, body = do("GET", "/entries/9", "", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("want missing entry to be not found, got %s: %s", resp.Status, body)
+	}
+
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"# Today",
+		"",
+		"  2022-01-02 buy oat milk due:2022-01-02",
+		"",
+		"# Someday",
+		"",
+		"  2022-01-01 fix the sink +home @house",
+		"",
+		"# Logged",
+		"",
+		"x 2022-01-02 2022-01-01 call mom +family",
+	}, "\n") + "\n"
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("file has unexpected contents (-got,+want):\n%s", diff)
+	}
+
+	resp, body = do("GET", "/stats", "", "")
+	var s stats
+	if err := json.Unmarshal([]byte(body), &s); err != nil || s.Completed != 1 {
+		t.Errorf("want stats with 1 completion, got %s: %s", resp.Status, body)
+	}
+}
+
+func TestServeIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("todo.txt", "#include team.txt\n\n# Next\n\n  2022-01-01 call mom\n  2022-01-01 renew passport s:2022-01-03\n")
+	write("team.txt", "# Next\n\n  2022-01-01 fix the printer\n")
+	clock := &clockFlags{now: "2022-01-02", tz: "UTC"}
+	srv := httptest.NewServer(newServer(filepath.Join(dir, "todo.txt"), clock))
+	defer srv.Close()
+
+	do := func(method, path, etag string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("If-Match", etag)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Entry 3 is the printer, from the included file.
+	etag := do("GET", "/entries", "").Header.Get("ETag")
+	if resp := do("POST", "/entries/3/complete", etag); resp.StatusCode != http.StatusOK {
+		t.Fatalf("complete failed with %s", resp.Status)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "team.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Logged\n\nx 2022-01-02 2022-01-01 fix the printer\n"; string(got) != want {
+		t.Errorf("team.txt has unexpected contents (-got,+want):\n%s", cmp.Diff(string(got), want))
+	}
+
+	// The next day the passport comes up to Today, and the entries are
+	// numbered differently.
+	etag = do("GET", "/entries", "").Header.Get("ETag")
+	clock.now = "2022-01-03"
+	if resp := do("POST", "/entries/1/complete", etag); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("want an ETag from the day before to fail, got %s", resp.Status)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+const (
+	// snoozeTag puts an entry off, like snooze:2d or snooze:fri. The
+	// formatter turns it into a scheduled date.
+	snoozeTag = "snooze"
+	// snoozedTag counts how many times an entry has been snoozed.
+	snoozedTag = "snoozed"
+)
+
+// snoozeUntil returns the scheduled date a snooze stands for, counting from
+// now. Offsets may leave out the +, as in snooze:2d.
+func snoozeUntil(now time.Time, when string) (string, error) {
+	until, hasClock, err := dates.ParseRelativeTime(now, when)
+	if err != nil {
+		var offsetErr error
+		if until, hasClock, offsetErr = dates.ParseRelativeTime(now, "+"+when); offsetErr != nil {
+			return "", err
+		}
+	}
+	if hasClock {
+		return until.Format(dateTimeFmt), nil
+	}
+	return until.Format(dateFmt), nil
+}
+
+// hasSnooze reports whether e has a snooze tag that can be understood. Other
+// snoozes stay where they are.
+func hasSnooze(now time.Time, e *ast.Entry) bool {
+	when, ok := e.Tag(snoozeTag)
+	if !ok {
+		return false
+	}
+	_, err := snoozeUntil(now, when)
+	return err == nil
+}
+
+// snoozeEntry turns a snooze tag into a scheduled date, rewriting the one the
+// entry had if any, and counts the snooze. A snooze that cannot be understood is
+// left for vogon check to point out.
+func snoozeEntry(now time.Time, e *ast.Entry) {
+	when, ok := e.Tag(snoozeTag)
+	if !ok {
+		return
+	}
+	until, err := snoozeUntil(now, when)
+	if err != nil {
+		return
+	}
+	e.RemoveTag(snoozeTag)
+	scheduled := false
+	ast.SliceRemove(&e.Description, func(dp *ast.DescriptionPart) bool {
+		if dp.SpecialTag == nil || !ast.StringIsScheduled(dp.SpecialTag.Key) {
+			return false
+		}
+		if scheduled {
+			return true
+		}
+		dp.SpecialTag.Value, scheduled = until, true
+		return false
+	})
+	if !scheduled {
+		e.Description = append(e.Description, &ast.DescriptionPart{
+			SpecialTag: &ast.SpecialTag{Key: "sched", Value: until},
+		})
+	}
+	setTag(e, snoozedTag, strconv.Itoa(snoozeCount(e)+1), true)
+}
+
+// snoozeCount returns how many times e has been snoozed.
+func snoozeCount(e *ast.Entry) int {
+	count, _ := e.Tag(snoozedTag)
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// runSnooze puts an entry off until later, the same as adding a snooze tag to
+// it by hand and formatting the file.
+func runSnooze(args []string) error {
+	fs := flag.NewFlagSet("snooze", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "todo.txt", "todo.txt file path to snooze an entry in")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vogon snooze [flags] entry when")
+		fmt.Fprintln(fs.Output(), "The entry is its id: tag, its number as vogon serve counts them, or words from it. When is like 2d, 1w, fri, or 2024-06-01.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("snooze needs an entry and when to snooze it until")
+	}
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	when := fs.Arg(1)
+	until, err := snoozeUntil(now, when)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(*filename)
+	if errors.Is(err, errLocked) {
+		return fmt.Errorf("%s is being written, try again", *filename)
+	} else if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := loadTodoFile(*filename)
+	if err != nil {
+		return err
+	}
+	f.Tree = compileTodoTxt(f.Tree, now)
+	e, err := findEntry(f.Tree, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if e.Completed {
+		return fmt.Errorf("%q is already done", entryWords(e))
+	}
+	e.RemoveTag(snoozeTag)
+	e.Description = append(e.Description, &ast.DescriptionPart{
+		SpecialTag: &ast.SpecialTag{Key: snoozeTag, Value: when},
+	})
+	f.Tree = compileTodoTxt(f.Tree, now)
+	if err := f.write(); err != nil {
+		return err
+	}
+	fmt.Printf("snoozed %q until %s\n", entryWords(e), until)
+	return nil
+}
+
+// findEntry finds the entry with the id: tag id. Failing that, id is the
+// entry's number in t, counting from 1 the way vogon serve does, or words
+// from the description of exactly one open entry.
+func findEntry(t ast.TodoTxt, id string) (*ast.Entry, error) {
+	entries := allEntries(t)
+	for _, entry := range entries {
+		if tag, ok := entry.Entry.Tag("id"); ok && tag == id {
+			return entry.Entry, nil
+		}
+	}
+	if n, err := strconv.Atoi(id); err == nil {
+		if n < 1 || n > len(entries) {
+			return nil, fmt.Errorf("no entry %d", n)
+		}
+		return entries[n-1].Entry, nil
+	}
+
+	var found []*ast.Entry
+	for _, entry := range entries {
+		if !entry.Entry.Completed && strings.Contains(entry.Entry.DescriptionText(), id) {
+			found = append(found, entry.Entry)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no open entry has %q", id)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("%d open entries have %q", len(found), id)
+}
+
+// snoozedReview is an open entry that keeps being put off.
+type snoozedReview struct {
+	Entry   string `json:"entry"`
+	Header  string `json:"header"`
+	Snoozed int    `json:"snoozed"`
+}
+
+// reviewSnoozed finds the open entries snoozed more than limit times, most
+// snoozed first.
+func reviewSnoozed(t ast.TodoTxt, limit int) []snoozedReview {
+	snoozed := []snoozedReview{}
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		if n := snoozeCount(e); !e.Completed && n > limit {
+			snoozed = append(snoozed, snoozedReview{Entry: entryWords(e), Header: heading, Snoozed: n})
+		}
+		return nil
+	})
+	sort.SliceStable(snoozed, func(i, j int) bool { return snoozed[i].Snoozed > snoozed[j].Snoozed })
+	return snoozed
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSnooze(t *testing.T) {
+	now := time.Date(2024, time.May, 6, 9, 0, 0, 0, time.UTC) // A Monday.
+	table := []struct {
+		name  string
+		input string
+		want  string
+	}{{
+		name:  "offset",
+		input: "# Today\n\n  2024-05-01 call mom snooze:2d\n",
+		want:  "# Scheduled\n\n  2024-05-01 call mom sched:2024-05-08 snoozed:1\n",
+	}, {
+		name:  "weekday and time",
+		input: "# Next\n\n  2024-05-01 call mom snooze:fri@9 snoozed:3\n",
+		want:  "# Scheduled\n\n  2024-05-01 call mom snoozed:4 sched:2024-05-10T09:00\n",
+	}, {
+		name:  "replaces the scheduled date",
+		input: "# Scheduled\n\n  2024-05-01 file taxes s:2024-05-07 snooze:+1w\n",
+		want:  "# Scheduled\n\n  2024-05-01 file taxes s:2024-05-13 snoozed:1\n",
+	}, {
+		name:  "until today",
+		input: "# Inbox\n\n  2024-05-01 water plants snooze:0d\n",
+		want:  "# Today\n\n  2024-05-01 water plants snoozed:1\n",
+	}, {
+		name:  "not understood",
+		input: "# Today\n\n  2024-05-01 read book snooze:someday\n",
+		want:  "# Today\n\n  2024-05-01 read book snooze:someday\n",
+	}}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := Fmt(now, &got, []byte(tc.input)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got.String(), tc.want); diff != "" {
+				t.Errorf("unexpected output (-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunSnooze(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.txt")
+	input := `# Today
+
+  2024-05-01 call mom
+  2024-05-01 call the bank id:bank
+
+# Logged
+
+x 2024-05-02 2024-05-01 call mom
+`
+	if err := os.WriteFile(filename, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"mom", "2d"},
+		{"bank", "tomorrow"},
+		{"2", "1w"},
+	} {
+		if err := runSnooze(append([]string{"-f", filename, "-now", "2024-05-06"}, args...)); err != nil {
+			t.Fatalf("snooze %v: %v", args, err)
+		}
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Scheduled
+
+  2024-05-01 call the bank id:bank sched:2024-05-07 snoozed:1
+  2024-05-01 call mom sched:2024-05-13 snoozed:2
+
+# Logged
+
+x 2024-05-02 2024-05-01 call mom
+`
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("unexpected file (-got,+want):\n%s", diff)
+	}
+
+	for _, id := range []string{"call", "5", "nobody"} {
+		if err := runSnooze([]string{"-f", filename, "-now", "2024-05-06", id, "2d"}); err == nil {
+			t.Errorf("snooze %q succeeded, want an error", id)
+		}
+	}
+}
+
+func TestReviewSnoozed(t *testing.T) {
+	now := time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC)
+	todo, err := parseTodoTxt([]byte(`# Next
+  2024-05-01 call mom snoozed:2
+  2024-05-01 call the bank +money snoozed:5
+  2024-05-01 fix bike snoozed:4
+x 2024-05-02 2024-05-01 pay rent snoozed:9
+`), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []snoozedReview{
+		{Entry: "call the bank +money", Header: "Next", Snoozed: 5},
+		{Entry: "fix bike", Header: "Next", Snoozed: 4},
+	}
+	if diff := cmp.Diff(reviewSnoozed(todo, 3), want); diff != "" {
+		t.Errorf("unexpected snoozed tasks (-got,+want):\n%s", diff)
+	}
+}
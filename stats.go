@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+type count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+type stats struct {
+	Since          string  `json:"since"`
+	Completed      int     `json:"completed"`
+	PerDay         []count `json:"per_day"`
+	PerWeek        []count `json:"per_week"`
+	PerMonth       []count `json:"per_month"`
+	PerProject     []count `json:"per_project"`
+	PerContext     []count `json:"per_context"`
+	AverageAgeDays float64 `json:"average_age_days"`
+	Open           []count `json:"open"`
+}
+
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "-", "todo.txt file path to report on")
+	weeks := fs.Int("weeks", 12, "Only count completions from this many weeks, or 0 for all")
+	format := fs.String("format", "text", "Output format, text, json, or csv")
+	fs.Parse(args)
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	t, err := loadTodoTxt(*filename, now)
+	if err != nil {
+		return err
+	}
+	s := computeStats(t, now, *weeks)
+
+	switch *format {
+	case "text":
+		return s.DumpText(os.Stdout)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case "csv":
+		return s.DumpCSV(os.Stdout)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+// computeStats counts completions in the Logbook since the start of the week
+// weeks weeks ago, and open entries per header.
+func computeStats(t ast.TodoTxt, now time.Time, weeks int) stats {
+	today := dates.DateOf(now)
+	start := today.StartOfWeek().AddDays(-7 * (weeks - 1))
+
+	var completed []dates.Date
+	var totalAge int
+	var aged int
+	perProject := make(map[string]int)
+	perContext := make(map[string]int)
+	var open []count
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		if !e.Completed {
+			if len(open) == 0 || open[len(open)-1].Key != heading {
+				open = append(open, count{Key: heading})
+			}
+			open[len(open)-1].Count++
+			return nil
+		}
+		if e.CompletionDate == nil || !e.CompletionDate.Valid() {
+			return nil
+		}
+		done := *e.CompletionDate
+		if weeks > 0 && done.Before(start) {
+			return nil
+		}
+		completed = append(completed, done)
+		for _, p := range e.Projects() {
+			perProject["+"+p]++
+		}
+		for _, c := range e.Contexts() {
+			perContext["@"+c]++
+		}
+		if e.CreationDate != nil && e.CreationDate.Valid() {
+			totalAge += e.CreationDate.DaysUntil(done)
+			aged++
+		}
+		return nil
+	})
+
+	s := stats{
+		Completed:  len(completed),
+		PerProject: sortedCounts(perProject),
+		PerContext: sortedCounts(perContext),
+		Open:       open,
+	}
+	if aged > 0 {
+		s.AverageAgeDays = float64(totalAge) / float64(aged)
+	}
+
+	if weeks <= 0 {
+		start = today
+		for _, done := range completed {
+			if done.Before(start) {
+				start = done
+			}
+		}
+	}
+	s.Since = start.String()
+	s.PerDay = bucket(completed, start, today, dayKey, func(d dates.Date) dates.Date { return d.AddDays(1) })
+	s.PerWeek = bucket(completed, start.StartOfWeek(), today, weekKey, func(d dates.Date) dates.Date { return d.AddDays(7) })
+	s.PerMonth = bucket(completed, start.StartOfMonth(), today, monthKey, func(d dates.Date) dates.Date { return d.AddMonths(1) })
+	return s
+}
+
+func dayKey(d dates.Date) string   { return d.String() }
+func monthKey(d dates.Date) string { return d.String()[:len("2006-01")] }
+func weekKey(d dates.Date) string {
+	year, week := d.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// bucket counts dates per period from start to end, including empty periods
+// so that the result can be drawn as a sparkline.
+func bucket(days []dates.Date, start, end dates.Date, key func(dates.Date) string, next func(dates.Date) dates.Date) []count {
+	counts := make(map[string]int)
+	for _, d := range days {
+		counts[key(d)]++
+	}
+	result := []count{}
+	for d := start; !d.After(end); d = next(d) {
+		k := key(d)
+		result = append(result, count{Key: k, Count: counts[k]})
+	}
+	return result
+}
+
+func sortedCounts(m map[string]int) []count {
+	result := make([]count, 0, len(m))
+	for k, v := range m {
+		result = append(result, count{Key: k, Count: v})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// sparkline draws counts as a row of bars of increasing height.
+func sparkline(counts []count) string {
+	ticks := []rune("▁▂▃▄▅▆▇█")
+	max := 0
+	for _, c := range counts {
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+	line := make([]rune, len(counts))
+	for i, c := range counts {
+		if max == 0 {
+			line[i] = ticks[0]
+			continue
+		}
+		line[i] = ticks[c.Count*(len(ticks)-1)/max]
+	}
+	return string(line)
+}
+
+func lastN[T any](l []T, n int) []T {
+	if len(l) <= n {
+		return l
+	}
+	return l[len(l)-n:]
+}
+
+func (s stats) DumpText(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "Completed since %s: %d\n", s.Since, s.Completed)
+	fmt.Fprintf(w, "Average age: %.1f days\n\n", s.AverageAgeDays)
+	days, weeks := lastN(s.PerDay, 28), lastN(s.PerWeek, 52)
+	fmt.Fprintf(w, "Last %d days:  %s\n", len(days), sparkline(days))
+	fmt.Fprintf(w, "Last %d weeks: %s\n", len(weeks), sparkline(weeks))
+
+	sections := []struct {
+		title  string
+		counts []count
+	}{
+		{"Per week", s.PerWeek},
+		{"Per month", s.PerMonth},
+		{"Per project", s.PerProject},
+		{"Per context", s.PerContext},
+		{"Open", s.Open},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		for _, c := range section.counts {
+			if c.Count == 0 {
+				continue // The sparklines already show the gaps.
+			}
+			fmt.Fprintf(w, "  %-12s %4d\n", c.Key, c.Count)
+		}
+	}
+	return w.Flush()
+}
+
+// DumpCSV writes one row per statistic, so that every report fits in the
+// same three columns.
+func (s stats) DumpCSV(out io.Writer) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"stat", "key", "value"})
+	w.Write([]string{"completed", s.Since, strconv.Itoa(s.Completed)})
+	w.Write([]string{"average_age_days", "", strconv.FormatFloat(s.AverageAgeDays, 'f', 2, 64)})
+	sections := []struct {
+		name   string
+		counts []count
+	}{
+		{"per_day", s.PerDay},
+		{"per_week", s.PerWeek},
+		{"per_month", s.PerMonth},
+		{"per_project", s.PerProject},
+		{"per_context", s.PerContext},
+		{"open", s.Open},
+	}
+	for _, section := range sections {
+		for _, c := range section.counts {
+			w.Write([]string{section.name, c.Key, strconv.Itoa(c.Count)})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestComputeStats(t *testing.T) {
+	input := strings.Join([]string{
+		"# Next",
+		"",
+		"  2024-05-01 call the bank +money @phone",
+		"",
+		"# Logged",
+		"",
+		"x 2024-05-08 2024-05-01 pay rent +money @home",
+		"x 2024-05-07 2024-05-07 fix the roof +house",
+		"x 2024-05-03 2024-05-02 mow the lawn +house @home",
+		"x 2024-03-01 2024-03-01 too old to count",
+	}, "\n")
+
+	now := time.Date(2024, time.May, 8, 12, 0, 0, 0, time.UTC)
+	todo, err := parseTodoTxt([]byte(input), now)
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+
+	got := computeStats(todo, now, 2)
+	if got.Since != "2024-04-29" {
+		t.Errorf("got since %q, want 2024-04-29", got.Since)
+	}
+	if got.Completed != 3 {
+		t.Errorf("got %d completed, want 3", got.Completed)
+	}
+	if want := 8.0 / 3; got.AverageAgeDays != want {
+		t.Errorf("got average age %f, want %f", got.AverageAgeDays, want)
+	}
+
+	want := map[string][]count{
+		"per week":    {{"2024-W18", 1}, {"2024-W19", 2}},
+		"per month":   {{"2024-04", 0}, {"2024-05", 3}},
+		"per project": {{"+house", 2}, {"+money", 1}},
+		"per context": {{"@home", 2}},
+		"open":        {{"Next", 1}},
+	}
+	gotCounts := map[string][]count{
+		"per week":    got.PerWeek,
+		"per month":   got.PerMonth,
+		"per project": got.PerProject,
+		"per context": got.PerContext,
+		"open":        got.Open,
+	}
+	if diff := cmp.Diff(gotCounts, want); diff != "" {
+		t.Errorf("computeStats returned unexpected counts (-got,+want):\n%s", diff)
+	}
+	if line, want := sparkline(got.PerDay), "▁▁▁▁█▁▁▁██"; line != want {
+		t.Errorf("got sparkline %q, want %q", line, want)
+	}
+}
@@ -32,6 +32,8 @@ syntax match date		'\d\{2,4\}-\d\{2\}-\d\{2\}' contains=NONE
 syntax match complete	'^x\>'						contains=NONE
 syntax match specialTag	'\(^\|\W\)[^[:blank:]]\+:[^[:blank:]]\+'	contains=NONE
 syntax match notestart  '^ *|'  contains=NONE
+syntax match prose	'^\s*\(>\|##\).*$'	contains=NONE
+syntax region todoComment	start='<!--'	end='-->'	contains=NONE
 
 highlight default link today	TodoToday
 highlight default link eve	TodoEve
@@ -47,5 +49,7 @@ highlight default link date		Comment
 highlight default link complete	Delimiter
 highlight default link specialTag		Comment
 highlight default link notestart  Comment
+highlight default link prose	Comment
+highlight default link todoComment	Comment
 
-syntax region todoFold start='^#' end=/^#/me=s-2 transparent fold
+syntax region todoFold start='^##\@!' end=/^##\@!/me=s-2 transparent fold
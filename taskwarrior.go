@@ -0,0 +1,342 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// twTimeFormat is how Taskwarrior writes times, always in UTC.
+const twTimeFormat = "20060102T150405Z"
+
+// twTask is a task as written by "task export" and read by "task import".
+type twTask struct {
+	UUID        string         `json:"uuid,omitempty"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	Entry       string         `json:"entry,omitempty"`
+	End         string         `json:"end,omitempty"`
+	Due         string         `json:"due,omitempty"`
+	Scheduled   string         `json:"scheduled,omitempty"`
+	Wait        string         `json:"wait,omitempty"`
+	Project     string         `json:"project,omitempty"`
+	Priority    string         `json:"priority,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Annotations []twAnnotation `json:"annotations,omitempty"`
+	Depends     twDepends      `json:"depends,omitempty"`
+	Recur       string         `json:"recur,omitempty"`
+	Parent      string         `json:"parent,omitempty"`
+}
+
+type twAnnotation struct {
+	Entry       string `json:"entry,omitempty"`
+	Description string `json:"description"`
+}
+
+// twDepends are the UUIDs a task depends on. Taskwarrior 2.6 and later write
+// them as a list, and older versions as one comma separated string.
+type twDepends []string
+
+func (d *twDepends) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*d = list
+		return nil
+	}
+	var joined string
+	if err := json.Unmarshal(data, &joined); err != nil {
+		return err
+	}
+	*d = strings.FieldsFunc(joined, func(r rune) bool { return r == ',' })
+	return nil
+}
+
+// twPriorities map Taskwarrior priorities to todo.txt ones.
+var twPriorities = map[string]string{"H": "A", "M": "B", "L": "C"}
+
+// twShortID is how long the id tags of imported tasks are. Taskwarrior knows
+// tasks by UUID, and the first few characters are enough to tell them apart.
+const twShortID = 8
+
+// twUUIDTag keeps the UUID of an imported task, so that exporting it again
+// updates the same task in Taskwarrior instead of adding another.
+const twUUIDTag = "uuid"
+
+// importTaskwarrior reads the JSON written by "task export". Deleted tasks
+// and the templates of recurring tasks are skipped; the instances of
+// recurring tasks are kept with a rec tag. Every task keeps its UUID in a uuid
+// tag. Tasks others depend on also get a short id tag, which the dep tags of
+// the others refer to.
+func importTaskwarrior(in io.Reader, loc *time.Location) ([]importedEntry, error) {
+	var tasks []twTask
+	if err := json.NewDecoder(in).Decode(&tasks); err != nil {
+		return nil, fmt.Errorf("reading Taskwarrior JSON: %w", err)
+	}
+	dependedOn := make(map[string]bool)
+	for _, task := range tasks {
+		for _, uuid := range task.Depends {
+			dependedOn[uuid] = true
+		}
+	}
+
+	var result []importedEntry
+	for i, task := range tasks {
+		if task.Status == "deleted" || task.Status == "recurring" {
+			continue
+		}
+		imported, err := twEntry(task, dependedOn[task.UUID], loc)
+		if err != nil {
+			return nil, fmt.Errorf("task %d (%q): %w", i+1, task.Description, err)
+		}
+		result = append(result, imported)
+	}
+	return result, nil
+}
+
+// twEntry builds an entry from the fields of a task. The description is
+// never read as todo.txt syntax.
+func twEntry(task twTask, needsID bool, loc *time.Location) (importedEntry, error) {
+	description, err := importedDescription(task.Description)
+	if err != nil {
+		return importedEntry{}, err
+	}
+	e := &ast.Entry{Completed: task.Status == "completed", Description: description}
+	if p, ok := twPriorities[task.Priority]; ok {
+		priority := "(" + p + ")"
+		e.Priority = &priority
+	}
+	for _, stamp := range []struct {
+		value string
+		date  **dates.Date
+	}{
+		{task.Entry, &e.CreationDate},
+		{task.End, &e.CompletionDate},
+	} {
+		if stamp.value == "" {
+			continue
+		}
+		t, err := time.Parse(twTimeFormat, stamp.value)
+		if err != nil {
+			return importedEntry{}, err
+		}
+		d := dates.DateOf(t.In(loc))
+		*stamp.date = &d
+	}
+	if !e.Completed {
+		e.CompletionDate = nil
+	}
+
+	if task.Project != "" {
+		addProject(e, task.Project)
+	}
+	for _, tag := range task.Tags {
+		addContext(e, tag)
+	}
+	for _, field := range []struct{ key, value string }{
+		{"due", task.Due},
+		{"sched", task.Scheduled},
+		{"wait", task.Wait},
+	} {
+		if field.value == "" {
+			continue
+		}
+		t, err := time.Parse(twTimeFormat, field.value)
+		if err != nil {
+			return importedEntry{}, fmt.Errorf("%s: %w", field.key, err)
+		}
+		addTag(e, field.key, twFormatTime(t.In(loc)))
+	}
+	if task.Recur != "" {
+		addTag(e, "rec", task.Recur)
+	}
+	if needsID {
+		addTag(e, "id", twID(task.UUID))
+	}
+	if len(task.Depends) > 0 {
+		var ids []string
+		for _, uuid := range task.Depends {
+			ids = append(ids, twID(uuid))
+		}
+		addTag(e, "dep", strings.Join(ids, ","))
+	}
+	if task.UUID != "" {
+		addTag(e, twUUIDTag, task.UUID)
+	}
+	for _, a := range task.Annotations {
+		text := strings.Fields(a.Description)
+		if t, err := time.Parse(twTimeFormat, a.Entry); err == nil {
+			text = append([]string{dates.DateOf(t.In(loc)).String()}, text...)
+		}
+		if len(text) > 0 {
+			e.Notes = append(e.Notes, ast.NoteLine{Text: text})
+		}
+	}
+
+	header := ""
+	if task.Status == "waiting" {
+		header = "Waiting"
+	}
+	return importedEntry{Header: header, Entry: e}, nil
+}
+
+// twFormatTime writes t as a date, with the time of day unless it is
+// midnight.
+func twFormatTime(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 {
+		return t.Format(dateFmt)
+	}
+	return t.Format(dateTimeFmt)
+}
+
+func twID(uuid string) string {
+	if len(uuid) > twShortID {
+		return uuid[:twShortID]
+	}
+	return uuid
+}
+
+// twUUID makes up a stable UUID for an entry's id tag, so that exporting the
+// same file twice gives the same UUIDs. Imported entries keep the UUID they
+// had instead.
+func twUUID(id string) string {
+	sum := sha1.Sum([]byte("vogon:" + id))
+	sum[6] = sum[6]&0x0f | 0x50 // Version 5, name based.
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// exportTaskwarrior writes entries as JSON for "task import". The first
+// project is the Taskwarrior project, contexts are tags, and notes are
+// annotations. Entries in the Waiting header with a wait tag are waiting.
+func exportTaskwarrior(out io.Writer, t ast.TodoTxt, now time.Time) error {
+	tasks := []twTask{}
+	reverse := make(map[string]string)
+	for tw, p := range twPriorities {
+		reverse[p] = tw
+	}
+	// The UUIDs of entries by id tag, for dep tags.
+	uuids := make(map[string]string)
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		id, hasID := e.Tag("id")
+		uuid, hasUUID := e.Tag(twUUIDTag)
+		if hasID && hasUUID {
+			uuids[id] = uuid
+		}
+		return nil
+	})
+
+	err := visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		task := twTask{Status: "pending", Tags: e.Contexts()}
+		if e.Completed {
+			task.Status = "completed"
+		}
+		if e.Priority != nil {
+			if p, ok := reverse[strings.Trim(*e.Priority, "()")]; ok {
+				task.Priority = p
+			} else {
+				task.Priority = "L"
+			}
+		}
+		if e.CreationDate != nil && e.CreationDate.Valid() {
+			task.Entry = e.CreationDate.Time(now.Location()).UTC().Format(twTimeFormat)
+		}
+		if e.Completed && e.CompletionDate != nil && e.CompletionDate.Valid() {
+			task.End = e.CompletionDate.Time(now.Location()).UTC().Format(twTimeFormat)
+		}
+
+		var words []string
+		for _, dp := range e.Description {
+			switch {
+			case dp.Project != nil:
+				if task.Project == "" {
+					task.Project = *dp.Project
+				} else {
+					words = append(words, "+"+*dp.Project)
+				}
+			case dp.Context != nil:
+			case dp.SpecialTag != nil:
+				if err := twExportTag(&task, dp.SpecialTag, uuids, now); err != nil {
+					return fmt.Errorf("%q: %w", e.DescriptionText(), err)
+				}
+			default:
+				words = append(words, dp.Text...)
+			}
+		}
+		task.Description = strings.Join(words, " ")
+		if heading == "Waiting" && task.Wait != "" && !e.Completed {
+			task.Status = "waiting"
+		}
+
+		for _, line := range e.Notes {
+			if len(line.Text) == 0 {
+				continue
+			}
+			a := twAnnotation{Description: strings.Join(line.Text, " ")}
+			if d, err := dates.ParseDate(line.Text[0]); err == nil {
+				a.Entry = d.Time(now.Location()).UTC().Format(twTimeFormat)
+				a.Description = strings.Join(line.Text[1:], " ")
+			}
+			task.Annotations = append(task.Annotations, a)
+		}
+		tasks = append(tasks, task)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tasks)
+}
+
+// twExportTag sets the field of task that a tag stands for. Other tags are
+// dropped, since Taskwarrior would need to be configured to know them.
+func twExportTag(task *twTask, tag *ast.SpecialTag, uuids map[string]string, now time.Time) error {
+	var field *string
+	switch {
+	case tag.Key == "due":
+		field = &task.Due
+	case ast.StringIsScheduled(tag.Key):
+		field = &task.Scheduled
+	case tag.Key == "wait":
+		field = &task.Wait
+	case tag.Key == "rec":
+		task.Recur = tag.Value
+		return nil
+	case tag.Key == twUUIDTag:
+		task.UUID = tag.Value
+		return nil
+	case tag.Key == "id":
+		if task.UUID == "" {
+			task.UUID = twUUID(tag.Value)
+		}
+		return nil
+	case tag.Key == "dep":
+		for _, id := range strings.Split(tag.Value, ",") {
+			uuid, ok := uuids[id]
+			if !ok {
+				uuid = twUUID(id)
+			}
+			task.Depends = append(task.Depends, uuid)
+		}
+		return nil
+	default:
+		return nil
+	}
+	if day, _, _ := dates.CutTime(tag.Value); moveTargets[day] || day == "t" {
+		return nil // Not a date.
+	}
+	t, _, err := dates.ParseRelativeTime(now, tag.Value)
+	if err != nil {
+		return err
+	}
+	*field = t.UTC().Format(twTimeFormat)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+func TestTaskwarrior(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join(dataPath, "taskwarrior.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
+	entries, err := importTaskwarrior(bytes.NewReader(input), time.UTC)
+	if err != nil {
+		t.Fatalf("importTaskwarrior failed: %v", err)
+	}
+	var imported ast.TodoTxt
+	addImported(&imported, entries)
+	imported = compileTodoTxt(imported, now)
+
+	var got bytes.Buffer
+	if err := imported.DumpText(&got); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(dataPath, "taskwarrior.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got.String(), string(want)); diff != "" {
+		t.Errorf("importTaskwarrior returned unexpected result (-got,+want):\n%s", diff)
+	}
+
+	got.Reset()
+	if err := exportTaskwarrior(&got, imported, now); err != nil {
+		t.Fatalf("exportTaskwarrior failed: %v", err)
+	}
+	want, err = os.ReadFile(filepath.Join(dataPath, "taskwarrior-export.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got.String(), string(want)); diff != "" {
+		t.Errorf("exportTaskwarrior returned unexpected result (-got,+want):\n%s", diff)
+	}
+
+	// The UUIDs survive the file being written and read again.
+	var written bytes.Buffer
+	if err := imported.DumpText(&written); err != nil {
+		t.Fatal(err)
+	}
+	reread, err := parseTodoTxt(written.Bytes(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Reset()
+	if err := exportTaskwarrior(&got, reread, now); err != nil {
+		t.Fatalf("exportTaskwarrior failed: %v", err)
+	}
+	if diff := cmp.Diff(got.String(), string(want)); diff != "" {
+		t.Errorf("exportTaskwarrior after reading the file returned unexpected result (-got,+want):\n%s", diff)
+	}
+}
+
+func TestTaskwarriorDescription(t *testing.T) {
+	imported, err := twEntry(twTask{Description: "x marks the due:friday spot", Status: "pending"}, false, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := imported.Entry
+	if e.Completed || len(e.Description) != 1 || e.DescriptionText() != "x marks the due:friday spot" {
+		t.Errorf("description was read as todo.txt: %+v", e)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/parse"
+)
+
+// verifyFormat checks formatted, the result of formatting input, before it is
+// written: formatting it again must not change it, and every entry and every
+// line of prose or comments in input must still be there, once. Losing a task
+// is the worst thing the formatter can do.
+func verifyFormat(now time.Time, input, formatted []byte) error {
+	before, err := parse.Parse("", input)
+	if err != nil {
+		return err
+	}
+	after, err := parse.Parse("", formatted)
+	if err != nil {
+		return fmt.Errorf("formatted file does not parse: %w", err)
+	}
+	if err := compareEntries(before, after); err != nil {
+		return err
+	}
+	if err := compareText(before, after); err != nil {
+		return err
+	}
+
+	again, err := formatTree(&after, now)
+	if err != nil {
+		return err
+	}
+	if line, ok := firstDifference(formatted, again); ok {
+		return fmt.Errorf("formatting again changes line %d: %q", line+1, lineAt(again, line))
+	}
+	return nil
+}
+
+// compareEntries checks that after has the same entries as before, going by
+// their descriptions without tags, which the formatter rewrites.
+func compareEntries(before, after ast.TodoTxt) error {
+	counts := make(map[string]int)
+	var total int
+	visitAllEntries(&before, func(heading string, e *ast.Entry) error {
+		counts[entryWords(e)]++
+		total++
+		return nil
+	})
+	visitAllEntries(&after, func(heading string, e *ast.Entry) error {
+		counts[entryWords(e)]--
+		total--
+		return nil
+	})
+
+	var lost, duplicated []string
+	for words, n := range counts {
+		switch {
+		case n > 0:
+			lost = append(lost, fmt.Sprintf("%q", words))
+		case n < 0:
+			duplicated = append(duplicated, fmt.Sprintf("%q", words))
+		}
+	}
+	sort.Strings(lost)
+	sort.Strings(duplicated)
+	switch {
+	case len(lost) > 0:
+		return fmt.Errorf("would lose %s", strings.Join(lost, ", "))
+	case len(duplicated) > 0:
+		return fmt.Errorf("would duplicate %s", strings.Join(duplicated, ", "))
+	case total != 0:
+		return fmt.Errorf("would change the number of entries by %d", -total)
+	}
+	return nil
+}
+
+// compareText checks that after has the same lines between entries as
+// before, other than blank lines and the Logbook headings the formatter
+// writes.
+func compareText(before, after ast.TodoTxt) error {
+	counts := make(map[string]int)
+	count := func(t ast.TodoTxt, n int) {
+		for _, g := range t.Groupings {
+			for _, b := range g.Blocks {
+				for _, line := range withoutPeriodHeadings(b.Leading) {
+					if line = strings.TrimSpace(line); line != "" {
+						counts[line] += n
+					}
+				}
+			}
+		}
+	}
+	count(before, 1)
+	count(after, -1)
+
+	var lost []string
+	for line, n := range counts {
+		if n > 0 {
+			lost = append(lost, fmt.Sprintf("%q", line))
+		}
+	}
+	sort.Strings(lost)
+	if len(lost) > 0 {
+		return fmt.Errorf("would lose the line %s", strings.Join(lost, ", "))
+	}
+	return nil
+}
+
+// entryWords is an entry's description without its tags.
+func entryWords(e *ast.Entry) string {
+	var words []string
+	for _, dp := range e.Description {
+		switch {
+		case dp.Project != nil:
+			words = append(words, "+"+*dp.Project)
+		case dp.Context != nil:
+			words = append(words, "@"+*dp.Context)
+		case dp.SpecialTag == nil:
+			words = append(words, dp.Text...)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// firstDifference returns the index of the first line that differs between a
+// and b.
+func firstDifference(a, b []byte) (int, bool) {
+	if bytes.Equal(a, b) {
+		return 0, false
+	}
+	linesA, linesB := bytes.Split(a, []byte("\n")), bytes.Split(b, []byte("\n"))
+	for i := range linesA {
+		if i >= len(linesB) || !bytes.Equal(linesA[i], linesB[i]) {
+			return i, true
+		}
+	}
+	return len(linesA), true
+}
+
+func lineAt(text []byte, i int) string {
+	lines := bytes.Split(text, []byte("\n"))
+	if i < len(lines) {
+		return string(lines[i])
+	}
+	return ""
+}
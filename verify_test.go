@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyFormat(t *testing.T) {
+	now := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	input := "call mom sched:today\nfix bike +home\nfix bike +home\n"
+	table := []struct {
+		name      string
+		input     string // If not the input above.
+		formatted string
+		wantErr   string
+	}{{
+		name:      "formatted",
+		formatted: "# Today\n\n  2022-01-01 call mom\n\n# Inbox\n\n  2022-01-01 fix bike +home\n  2022-01-01 fix bike +home\n",
+		wantErr:   "formatting again changes line 1",
+	}, {
+		name:      "stable",
+		formatted: "# Inbox\n\n  2022-01-01 fix bike +home\n  2022-01-01 fix bike +home\n\n# Today\n\n  2022-01-01 call mom\n",
+	}, {
+		name:      "lost",
+		formatted: "# Inbox\n\n  2022-01-01 fix bike +home\n\n# Today\n\n  2022-01-01 call mom\n",
+		wantErr:   `would lose "fix bike +home"`,
+	}, {
+		name:      "duplicated",
+		formatted: "# Inbox\n\n  2022-01-01 fix bike +home\n  2022-01-01 fix bike +home\n\n# Today\n\n  2022-01-01 call mom\n  2022-01-01 call mom\n",
+		wantErr:   `would duplicate "call mom"`,
+	}, {
+		name:      "changed",
+		formatted: "# Inbox\n\n  2022-01-01 fix bike +home\n  2022-01-01 fix the bike +home\n\n# Today\n\n  2022-01-01 call mom\n",
+		wantErr:   `would lose "fix bike +home"`,
+	}, {
+		name:      "lost prose",
+		input:     "# Next\n\n> keep this\n\n  2022-01-01 fix bike\n",
+		formatted: "# Next\n\n  2022-01-01 fix bike\n",
+		wantErr:   `would lose the line "> keep this"`,
+	}}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			in := input
+			if tc.input != "" {
+				in = tc.input
+			}
+			err := verifyFormat(now, []byte(in), []byte(tc.formatted))
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("verifyFormat failed: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("verifyFormat returned %v, want an error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestFmtConverges(t *testing.T) {
+	now := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var input strings.Builder
+	input.WriteString("# Logged\n\n")
+	for day := 1; day <= 60; day += 3 {
+		d := time.Date(2021, time.October, day, 0, 0, 0, 0, time.UTC).Format(dateFmt)
+		input.WriteString("x " + d + " " + d + " done on " + d + "\n")
+	}
+
+	var once strings.Builder
+	if err := Fmt(now, &once, []byte(input.String())); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyFormat(now, []byte(input.String()), []byte(once.String())); err != nil {
+		t.Errorf("formatting once did not settle: %v", err)
+	}
+}
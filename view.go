@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+)
+
+// nextActionHeaders are the headers that count as a next action for a
+// project during review.
+var nextActionHeaders = map[string]bool{
+	"Today": true,
+	"Next":  true,
+}
+
+// runView prints a read-only copy of the todo file regrouped by project,
+// context, or tag value. Each entry is annotated with the header it lives
+// under, and groups with nothing in Today or Next are flagged.
+func runView(args []string) error {
+	fs := flag.NewFlagSet("view", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "-", "todo.txt file path to view")
+	by := fs.String("by", "project", "Group entries by project, context, or tag:<key>")
+	fs.Parse(args)
+
+	keys, err := viewKeys(*by)
+	if err != nil {
+		return err
+	}
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	t, err := loadTodoTxt(*filename, now)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if err := regroup(t, keys).DumpText(out); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// viewKeys returns a function that lists the groups an entry belongs to.
+func viewKeys(by string) (func(e *ast.Entry) []string, error) {
+	switch {
+	case by == "project":
+		return func(e *ast.Entry) []string {
+			return prefixAll("+", e.Projects())
+		}, nil
+	case by == "context":
+		return func(e *ast.Entry) []string {
+			return prefixAll("@", e.Contexts())
+		}, nil
+	case strings.HasPrefix(by, "tag:") && len(by) > len("tag:"):
+		key := by[len("tag:"):]
+		return func(e *ast.Entry) []string {
+			if value, ok := e.Tag(key); ok {
+				return []string{key + ":" + value}
+			}
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("cannot view by %q, want project, context, or tag:<key>", by)
+}
+
+func prefixAll(prefix string, l []string) []string {
+	result := make([]string, len(l))
+	for i := range l {
+		result[i] = prefix + l[i]
+	}
+	return result
+}
+
+// regroup builds a new document with one heading per group. Completed
+// entries are left out.
+func regroup(t ast.TodoTxt, keys func(e *ast.Entry) []string) ast.TodoTxt {
+	groups := make(map[string][]*ast.Entry)
+	hasNext := make(map[string]bool)
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		if e.Completed {
+			return nil
+		}
+		for _, key := range keys(e) {
+			groups[key] = append(groups[key], annotateHeader(e, heading))
+			if nextActionHeaders[heading] {
+				hasNext[key] = true
+			}
+		}
+		return nil
+	})
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result ast.TodoTxt
+	for _, name := range names {
+		header := []string{name}
+		if !hasNext[name] {
+			header = append(header, "(no next action)")
+		}
+		result.Groupings = append(result.Groupings, ast.Grouping{
+			Header: header,
+			Blocks: []ast.Block{{Children: groups[name]}},
+		})
+	}
+	return result
+}
+
+// annotateHeader returns a copy of the entry tagged with the header it
+// lives under.
+func annotateHeader(e *ast.Entry, heading string) *ast.Entry {
+	annotated := *e
+	annotated.Description = append(slices.Clip(e.Description), &ast.DescriptionPart{
+		SpecialTag: &ast.SpecialTag{
+			Key:   "in",
+			Value: strings.ReplaceAll(heading, " ", "_"),
+		},
+	})
+	return &annotated
+}
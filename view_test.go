@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestView(t *testing.T) {
+	input := strings.Join([]string{
+		"# Inbox",
+		"",
+		"  2022-01-01 file taxes +money @home",
+		"",
+		"# Next",
+		"",
+		"  2022-01-01 call the bank +money @phone prio:high",
+		"",
+		"# Someday",
+		"",
+		"  2022-01-01 paint the shed +house @home",
+		"",
+		"# Logged",
+		"",
+		"x 2022-01-01 2022-01-01 fix the roof +house",
+	}, "\n")
+
+	table := []struct {
+		by   string
+		want string
+	}{{
+		by: "project",
+		want: strings.Join([]string{
+			"# +house (no next action)",
+			"",
+			"  2022-01-01 paint the shed +house @home in:Someday",
+			"",
+			"# +money",
+			"",
+			"  2022-01-01 file taxes +money @home in:Inbox",
+			"  2022-01-01 call the bank +money @phone prio:high in:Next",
+		}, "\n") + "\n",
+	}, {
+		by: "context",
+		want: strings.Join([]string{
+			"# @home (no next action)",
+			"",
+			"  2022-01-01 file taxes +money @home in:Inbox",
+			"  2022-01-01 paint the shed +house @home in:Someday",
+			"",
+			"# @phone",
+			"",
+			"  2022-01-01 call the bank +money @phone prio:high in:Next",
+		}, "\n") + "\n",
+	}, {
+		by: "tag:prio",
+		want: strings.Join([]string{
+			"# prio:high",
+			"",
+			"  2022-01-01 call the bank +money @phone prio:high in:Next",
+		}, "\n") + "\n",
+	}}
+
+	now := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
+	for _, tc := range table {
+		t.Run(tc.by, func(t *testing.T) {
+			keys, err := viewKeys(tc.by)
+			if err != nil {
+				t.Fatalf("viewKeys(%q) failed: %v", tc.by, err)
+			}
+			todo, err := parseTodoTxt([]byte(input), now)
+			if err != nil {
+				t.Fatalf("failed to parse input: %v", err)
+			}
+			var got bytes.Buffer
+			if err := regroup(todo, keys).DumpText(&got); err != nil {
+				t.Fatalf("DumpText failed: %v", err)
+			}
+			if diff := cmp.Diff(got.String(), tc.want); diff != "" {
+				t.Errorf("view returned unexpected result (-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/ast"
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+// ownerTags name who a task is waiting on, as in waiting:@sam or for:sam.
+var ownerTags = []string{"waiting", "for"}
+
+// followTag is the date to follow up on a task that is waiting on someone.
+const followTag = "follow"
+
+// followUpPrefix starts the description of a task that is back in Today to
+// follow up on, as in "follow up with sam: send the slides".
+const followUpPrefix = "follow up with"
+
+// entryOwner returns who an entry is waiting on, without the @.
+func entryOwner(e *ast.Entry) (string, bool) {
+	for _, key := range ownerTags {
+		if owner, ok := e.Tag(key); ok && strings.TrimPrefix(owner, "@") != "" {
+			return strings.TrimPrefix(owner, "@"), true
+		}
+	}
+	return "", false
+}
+
+// followUpDay returns the day to follow up on e, if it has one.
+func followUpDay(now time.Time, e *ast.Entry) (dates.Date, bool) {
+	follow, ok := e.Tag(followTag)
+	if !ok {
+		return dates.Date{}, false
+	}
+	day, err := dates.ParseDay(now, follow)
+	return day, err == nil
+}
+
+// isDelegated reports whether an open entry under header is waiting on
+// someone and belongs in Waiting: it was just written down, or it has a
+// follow-up date yet to come. Scheduled entries go to Scheduled instead.
+func isDelegated(now time.Time, header string, e *ast.Entry) bool {
+	if _, ok := entryOwner(e); !ok || e.Completed {
+		return false
+	}
+	if _, ok := e.ScheduledFor(); ok {
+		return false
+	}
+	if header == "" || header == "Inbox" {
+		return true
+	}
+	day, ok := followUpDay(now, e)
+	return ok && day.After(dates.DateOf(now))
+}
+
+// isFollowUp reports whether it is time to follow up on an entry that is
+// waiting on someone.
+func isFollowUp(now time.Time, e *ast.Entry) bool {
+	if _, ok := entryOwner(e); !ok || e.Completed {
+		return false
+	}
+	day, ok := followUpDay(now, e)
+	return ok && !day.After(dates.DateOf(now))
+}
+
+// followUp turns an entry whose follow-up date has come into a task to follow
+// up with its owner. The follow-up date goes, so that a new one sends the
+// entry back to Waiting.
+func followUp(now time.Time, e *ast.Entry) {
+	if !isFollowUp(now, e) {
+		return
+	}
+	e.RemoveTag(followTag)
+	if strings.HasPrefix(e.DescriptionText(), followUpPrefix+" ") {
+		return
+	}
+	owner, _ := entryOwner(e)
+	prefix := strings.Fields(followUpPrefix)
+	e.Description = append([]*ast.DescriptionPart{{
+		Text: append(prefix, owner+":"),
+	}}, e.Description...)
+}
+
+// delegation is a task waiting on someone.
+type delegation struct {
+	Entry  string `json:"entry"`
+	Header string `json:"header"`
+	Age    int    `json:"age_days"`
+	Follow string `json:"follow,omitempty"`
+}
+
+// delegations are the tasks waiting on one person.
+type delegations struct {
+	Person string       `json:"person"`
+	Tasks  []delegation `json:"tasks"`
+}
+
+// runWaiting lists the open tasks waiting on someone, by person.
+func runWaiting(args []string) error {
+	fs := flag.NewFlagSet("waiting", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "-", "todo.txt file path to read")
+	format := fs.String("format", "text", "Output format, text or json")
+	fs.Parse(args)
+
+	now, err := clock.Now()
+	if err != nil {
+		return err
+	}
+	t, err := loadTodoTxt(*filename, now)
+	if err != nil {
+		return err
+	}
+	people := waitingOn(t, now)
+	switch *format {
+	case "text":
+		return dumpWaiting(os.Stdout, people)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(people)
+	}
+	return fmt.Errorf("unknown format %q", *format)
+}
+
+// waitingOn groups the open tasks waiting on someone by person, in order of
+// name, with the oldest tasks first. A task's age counts from its creation
+// date.
+func waitingOn(t ast.TodoTxt, now time.Time) []delegations {
+	byPerson := make(map[string][]delegation)
+	today := dates.DateOf(now)
+	visitAllEntries(&t, func(heading string, e *ast.Entry) error {
+		owner, ok := entryOwner(e)
+		if !ok || e.Completed {
+			return nil
+		}
+		// The person is already in the heading.
+		words := strings.TrimPrefix(entryWords(e), followUpPrefix+" "+owner+": ")
+		d := delegation{Entry: words, Header: heading}
+		if e.CreationDate != nil && e.CreationDate.Valid() {
+			d.Age = e.CreationDate.DaysUntil(today)
+		}
+		d.Follow, _ = e.Tag(followTag)
+		byPerson[owner] = append(byPerson[owner], d)
+		return nil
+	})
+
+	people := []delegations{}
+	for person, tasks := range byPerson {
+		sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Age > tasks[j].Age })
+		people = append(people, delegations{Person: person, Tasks: tasks})
+	}
+	sort.Slice(people, func(i, j int) bool { return people[i].Person < people[j].Person })
+	return people
+}
+
+func dumpWaiting(out io.Writer, people []delegations) error {
+	w := bufio.NewWriter(out)
+	for i, p := range people {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s (%d)\n", p.Person, len(p.Tasks))
+		for _, d := range p.Tasks {
+			fmt.Fprintf(w, "  %4dd  %s", d.Age, d.Entry)
+			if d.Follow != "" {
+				fmt.Fprintf(w, " (follow up %s)", d.Follow)
+			} else if d.Header == "Today" {
+				fmt.Fprintf(w, " (follow up today)")
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return w.Flush()
+}
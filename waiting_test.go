@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWaitingFollowUp(t *testing.T) {
+	input := `# Inbox
+
+  2024-04-20 send the slides waiting:@sam follow:fri
+  2024-05-01 review the budget for:alex
+  2024-05-01 buy a gift for:mom s:sat
+
+# Waiting
+
+  2024-04-01 get the contract back for:@legal follow:2024-05-06
+  2024-04-01 hear back from the landlord
+
+# Today
+
+  2024-04-02 follow up with kim: order chairs for:kim follow:+2w
+`
+	monday := time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC)
+	want := `# Today
+
+  2024-04-01 follow up with legal: get the contract back for:@legal
+
+# Scheduled
+
+  2024-05-01 buy a gift for:mom s:2024-05-11
+
+# Waiting
+
+  2024-04-20 send the slides waiting:@sam follow:2024-05-10
+  2024-05-01 review the budget for:alex
+  2024-04-01 hear back from the landlord
+  2024-04-02 follow up with kim: order chairs for:kim follow:2024-05-20
+`
+	var got bytes.Buffer
+	if err := Fmt(monday, &got, []byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("unexpected output on Monday (-got,+want):\n%s", diff)
+	}
+
+	friday := monday.AddDate(0, 0, 4)
+	want = `# Today
+
+  2024-04-01 follow up with legal: get the contract back for:@legal
+  2024-04-20 follow up with sam: send the slides waiting:@sam
+
+# Scheduled
+
+  2024-05-01 buy a gift for:mom s:2024-05-11
+
+# Waiting
+
+  2024-05-01 review the budget for:alex
+  2024-04-01 hear back from the landlord
+  2024-04-02 follow up with kim: order chairs for:kim follow:2024-05-20
+`
+	input = got.String()
+	got.Reset()
+	if err := Fmt(friday, &got, []byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("unexpected output on Friday (-got,+want):\n%s", diff)
+	}
+}
+
+func TestWaitingOn(t *testing.T) {
+	input := strings.Join([]string{
+		"# Today",
+		"  2024-04-01 follow up with legal: get the contract back for:@legal",
+		"# Next",
+		"  2024-05-02 proofread the essay waiting:@sam",
+		"# Waiting",
+		"  2024-04-20 send the slides +talk waiting:@sam follow:2024-05-13",
+		"  2024-05-01 hear back from the landlord",
+		"# Logged",
+		"x 2024-05-03 2024-05-01 sign the lease for:@legal",
+	}, "\n")
+	want := strings.Join([]string{
+		"legal (1)",
+		"    39d  get the contract back (follow up today)",
+		"",
+		"sam (2)",
+		"    20d  send the slides +talk (follow up 2024-05-13)",
+		"     8d  proofread the essay",
+	}, "\n") + "\n"
+
+	now := time.Date(2024, time.May, 10, 0, 0, 0, 0, time.UTC)
+	todo, err := parseTodoTxt([]byte(input), now)
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+	var got bytes.Buffer
+	if err := dumpWaiting(&got, waitingOn(todo, now)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("unexpected delegations (-got,+want):\n%s", diff)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spencer-p/vogon/pkg/dates"
+)
+
+const (
+	// lockSuffix names the file that marks a todo file as being written,
+	// either by vogon watch or by an editor that cooperates with it.
+	lockSuffix = ".lock"
+	// staleLock is how old a lock has to be to have been left behind by a
+	// crash rather than held by a running writer.
+	staleLock = time.Minute
+)
+
+var errLocked = errors.New("file is locked")
+
+// runWatch keeps a todo file formatted while it is open elsewhere. It formats
+// the file once the file has been left alone for a little while after a
+// change, and again whenever a new day starts.
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	clock := newClockFlags(fs)
+	filename := fs.String("f", "todo.txt", "todo.txt file path to watch")
+	interval := fs.Duration("interval", time.Second, "How often to look at the file")
+	debounce := fs.Duration("debounce", 2*time.Second, "How long to wait after a change before formatting")
+	journal := fs.String("journal", "", "Record what formatting changes about entries in this file, for vogon undo. Defaults to the -f file with "+journalSuffix+" added")
+	fs.Parse(args)
+
+	if *filename == "-" {
+		return fmt.Errorf("cannot watch stdin")
+	}
+	w := &watcher{filename: *filename, debounce: *debounce, journal: journalFor(*filename, *journal)}
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for t := time.Now(); ; t = <-ticker.C {
+		now, err := clock.Now()
+		if err != nil {
+			return err
+		}
+		if err := w.tick(t, now); err != nil {
+			// The file may be half written or briefly missing while an
+			// editor saves it, so keep going.
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// fileStamp is what the watcher knows about a file without reading it.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statStamp(filename string) (fileStamp, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+type watcher struct {
+	filename string
+	debounce time.Duration
+	journal  string // Where to record what formatting changes, if anywhere.
+
+	stamp   fileStamp
+	changed time.Time // When the file changed, or zero if it is formatted.
+	day     dates.Date
+}
+
+// tick looks at the file at wall clock time t and formats it for now if it
+// has settled after a change or the day has turned over.
+func (w *watcher) tick(t, now time.Time) error {
+	stamp, err := statStamp(w.filename)
+	if err != nil {
+		return err
+	}
+	if stamp != w.stamp {
+		w.stamp = stamp
+		w.changed = t
+	}
+
+	today := dates.DateOf(now)
+	settled := !w.changed.IsZero() && t.Sub(w.changed) >= w.debounce
+	if !settled && today.Equal(w.day) {
+		return nil
+	}
+
+	err = w.format(now)
+	if errors.Is(err, errLocked) {
+		return nil // Try again on the next tick.
+	}
+	// Give up on this version of the file even if it failed to format, so
+	// that the same error is not repeated every tick.
+	w.changed = time.Time{}
+	w.day = today
+	return err
+}
+
+// format formats the file in place, unless it changes while being formatted.
+func (w *watcher) format(now time.Time) error {
+	unlock, err := lockFile(w.filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	before, err := statStamp(w.filename)
+	if err != nil {
+		return err
+	}
+	f, err := loadTodoFile(w.filename)
+	if err != nil {
+		return err
+	}
+	snapshot := snapshotEntries(&f.Tree)
+	f.Tree = compileTodoTxt(f.Tree, now)
+	if after, err := statStamp(w.filename); err != nil || after != before {
+		// Someone else wrote the file. It will settle and be formatted
+		// again.
+		return errLocked
+	}
+	if err := f.write(); err != nil {
+		return err
+	}
+	if err := appendJournal(w.journal, now, journalChanges(snapshot, &f.Tree)); err != nil {
+		return err
+	}
+	// Our own write is not a change to react to.
+	w.stamp, err = statStamp(w.filename)
+	return err
+}
+
+// lockFile takes the lock for filename, or fails with errLocked if somebody
+// else holds it. The returned function releases the lock.
+func lockFile(filename string) (unlock func(), err error) {
+	lock := filename + lockSuffix
+	f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if errors.Is(err, os.ErrExist) {
+		info, statErr := os.Stat(lock)
+		if statErr != nil || time.Since(info.ModTime()) < staleLock {
+			return nil, errLocked
+		}
+		// Left behind by a crash; take it over.
+		os.Remove(lock)
+		f, err = os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errors.Is(err, os.ErrExist) {
+			return nil, errLocked
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return func() { os.Remove(lock) }, nil
+}
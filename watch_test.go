@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.txt")
+	write := func(contents string) {
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(want string) {
+		t.Helper()
+		got, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("want file contents %q, got %q", want, got)
+		}
+	}
+
+	write("  do it due:2022-01-02\n")
+	w := &watcher{filename: filename, debounce: 2 * time.Second}
+	start := time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	// The first look formats the file.
+	if err := w.tick(start, start); err != nil {
+		t.Fatal(err)
+	}
+	expect("# Inbox\n\n  2022-01-01 do it due:2022-01-02\n")
+
+	// A change waits for the file to settle.
+	write("  do it due:2022-01-02\n  and this\n")
+	if err := w.tick(start.Add(time.Second), start); err != nil {
+		t.Fatal(err)
+	}
+	expect("  do it due:2022-01-02\n  and this\n")
+	if err := w.tick(start.Add(3*time.Second), start); err != nil {
+		t.Fatal(err)
+	}
+	expect("# Inbox\n\n  2022-01-01 do it due:2022-01-02\n  2022-01-01 and this\n")
+
+	// A new day formats the file, unless it is locked.
+	tomorrow := start.AddDate(0, 0, 1)
+	unlock, err := lockFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.tick(tomorrow, tomorrow); err != nil {
+		t.Fatal(err)
+	}
+	expect("# Inbox\n\n  2022-01-01 do it due:2022-01-02\n  2022-01-01 and this\n")
+	unlock()
+	if err := w.tick(tomorrow.Add(time.Second), tomorrow); err != nil {
+		t.Fatal(err)
+	}
+	expect("# Inbox\n\n  2022-01-01 and this\n\n# Today\n\n  2022-01-01 do it due:2022-01-02\n")
+}